@@ -0,0 +1,202 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cache implements a sharded LRU cache of table blocks.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// Number of shards, must be a power of 2
+	numShardBits = 4
+	numShards    = 1 << numShardBits
+
+	// Set in the ids returned by NewID, apart from the file numbers
+	newIDFlag = uint64(1) << 63
+)
+
+// Key identifies a cached block: the cache id of the table, its file number
+// or an id returned by NewID, and the offset of the block in the table.
+type Key struct {
+	ID     uint64
+	Offset uint64
+}
+
+func (self Key) hash() uint32 {
+	h := self.ID * 0x9e3779b97f4a7c15 ^ self.Offset * 0xc2b2ae3d27d4eb4f
+	h ^= h >> 29
+	return uint32(h)
+}
+
+// Stats holds the cache counters.
+type Stats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+	// Sum of the charges of the cached values
+	Size int64
+}
+
+//---------------------------------------------------------------------------------------
+// LRU Cache
+//---------------------------------------------------------------------------------------
+
+// LRU is a cache bounded by the total size of its values. When the size 
+// of a shard goes over its capacity, the least recently used values are 
+// evicted. It is safe for concurrent use.
+//
+// Cached values are shared, they must not be modified.
+type LRU struct {
+	shards [numShards]shard
+
+	lastID uint64
+	hits   uint64
+	misses uint64
+}
+
+// Creates a cache holding up to capacity bytes of values.
+func NewLRU(capacity int64) *LRU {
+	c := new(LRU)
+
+	perShard := (capacity + numShards - 1) / numShards
+	for i := range c.shards {
+		c.shards[i].capacity = perShard
+		c.shards[i].table    = make(map[Key]*list.Element)
+		c.shards[i].list.Init()
+	}
+	return c
+}
+
+// Returns a new id, used by the tables without file number to partition 
+// the cache key space. The most significant bit of the ids is set, it is
+// never set in file numbers.
+func (self *LRU) NewID() uint64 {
+	return atomic.AddUint64(&self.lastID, 1) | newIDFlag
+}
+
+// Returns the value cached for the key.
+func (self *LRU) Get(key Key) ([]byte, bool) {
+	value, ok := self.shard(key).get(key)
+	if ok {
+		atomic.AddUint64(&self.hits, 1)
+	} else {
+		atomic.AddUint64(&self.misses, 1)
+	}
+	return value, ok
+}
+
+// Inserts the value for the key, replacing any previous value. The value 
+// is charged by its length against the cache capacity.
+func (self *LRU) Insert(key Key, value []byte) {
+	self.shard(key).insert(key, value)
+}
+
+// Removes the value cached for the key.
+func (self *LRU) Erase(key Key) {
+	self.shard(key).erase(key)
+}
+
+// Returns the cache capacity in bytes.
+func (self *LRU) Capacity() int64 {
+	var capacity int64
+	for i := range self.shards {
+		capacity += self.shards[i].capacity
+	}
+	return capacity
+}
+
+func (self *LRU) Stats() Stats {
+	stats := Stats{
+		Hits:   atomic.LoadUint64(&self.hits),
+		Misses: atomic.LoadUint64(&self.misses),
+	}
+
+	for i := range self.shards {
+		s := &self.shards[i]
+
+		s.mu.Lock()
+		stats.Entries += s.list.Len()
+		stats.Size    += s.usage
+		s.mu.Unlock()
+	}
+	return stats
+}
+
+func (self *LRU) shard(key Key) *shard {
+	return &self.shards[key.hash() >> (32 - numShardBits)]
+}
+
+//---------------------------------------------------------------------------------------
+// Shard
+//---------------------------------------------------------------------------------------
+
+type entry struct {
+	key   Key
+	value []byte
+}
+
+type shard struct {
+	mu sync.Mutex
+
+	capacity int64
+	usage    int64
+
+	table map[Key]*list.Element
+	// Front is the most recently used entry
+	list list.List
+}
+
+func (self *shard) get(key Key) ([]byte, bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	e, ok := self.table[key]
+	if !ok {
+		return nil, false
+	}
+	self.list.MoveToFront(e)
+
+	return e.Value.(*entry).value, true
+}
+
+func (self *shard) insert(key Key, value []byte) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if e, ok := self.table[key]; ok {
+		self.remove(e)
+	}
+
+	if int64(len(value)) > self.capacity {
+		// Would evict everything else and still not fit
+		return
+	}
+
+	self.table[key] = self.list.PushFront(&entry{key, value})
+	self.usage += int64(len(value))
+
+	for self.usage > self.capacity {
+		self.remove(self.list.Back())
+	}
+}
+
+func (self *shard) erase(key Key) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if e, ok := self.table[key]; ok {
+		self.remove(e)
+	}
+}
+
+func (self *shard) remove(e *list.Element) {
+	en := self.list.Remove(e).(*entry)
+
+	delete(self.table, en.key)
+	self.usage -= int64(len(en.value))
+}
@@ -0,0 +1,130 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Returns n keys stored in the first shard, so that capacity tests are
+// not spread over all the shards.
+func shardKeys(c *LRU, n int) []Key {
+	var keys []Key
+	for i := uint64(0); len(keys) < n; i++ {
+		key := Key{ID: 1, Offset: i}
+		if c.shard(key) == &c.shards[0] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestLRUGetInsert(t *testing.T) {
+	c := NewLRU(1 << 20)
+
+	if _, ok := c.Get(Key{1, 0}); ok {
+		t.Error("Empty cache should not have a value")
+	}
+
+	c.Insert(Key{1, 0}, []byte("one"))
+	c.Insert(Key{2, 0}, []byte("two"))
+
+	if value, ok := c.Get(Key{1, 0}); !ok || string(value) != "one" {
+		t.Errorf("Should be one, got %s %v", value, ok)
+	}
+	if value, ok := c.Get(Key{2, 0}); !ok || string(value) != "two" {
+		t.Errorf("Should be two, got %s %v", value, ok)
+	}
+
+	c.Insert(Key{1, 0}, []byte("uno"))
+	if value, _ := c.Get(Key{1, 0}); string(value) != "uno" {
+		t.Errorf("Should be uno, got %s", value)
+	}
+
+	c.Erase(Key{1, 0})
+	if _, ok := c.Get(Key{1, 0}); ok {
+		t.Error("Erased key should not have a value")
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 3 || stats.Misses != 2 {
+		t.Errorf("Should be 3 hits and 2 misses, got %+v", stats)
+	}
+	if stats.Entries != 1 || stats.Size != 3 {
+		t.Errorf("Should be 1 entry of 3 bytes, got %+v", stats)
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	c := NewLRU(numShards * 100)
+	keys := shardKeys(c, 20)
+
+	value := bytes.Repeat([]byte("x"), 10)
+	for _, key := range keys[:10] {
+		c.Insert(key, value)
+	}
+
+	// Use the first key, the second one is now the least recently used
+	c.Get(keys[0])
+	c.Insert(keys[10], value)
+
+	if _, ok := c.Get(keys[1]); ok {
+		t.Error("Least recently used key should be evicted")
+	}
+	for _, key := range append([]Key{keys[0]}, keys[2:11]...) {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Key %v should still be cached", key)
+		}
+	}
+
+	if size := c.Stats().Size; size != 100 {
+		t.Errorf("Size should be 100, got %d", size)
+	}
+
+	// Values larger than a shard are not cached
+	c.Insert(keys[11], make([]byte, 101))
+	if _, ok := c.Get(keys[11]); ok {
+		t.Error("Value larger than the capacity should not be cached")
+	}
+}
+
+func TestLRUNewID(t *testing.T) {
+	c := NewLRU(0)
+	a, b := c.NewID(), c.NewID()
+	if a == b {
+		t.Errorf("Ids should be unique, got %d and %d", a, b)
+	}
+	if a & newIDFlag == 0 || b & newIDFlag == 0 {
+		t.Errorf("Ids should not collide with file numbers, got %d and %d", a, b)
+	}
+}
+
+func TestLRUConcurrent(t *testing.T) {
+	c := NewLRU(1 << 16)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := Key{uint64(g), uint64(i % 100)}
+				if value, ok := c.Get(key); ok && string(value) != fmt.Sprint(key) {
+					t.Errorf("Wrong value for %v: %s", key, value)
+					return
+				}
+				c.Insert(key, []byte(fmt.Sprint(key)))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if stats := c.Stats(); stats.Size > c.Capacity() {
+		t.Errorf("Size %d over capacity %d", stats.Size, c.Capacity())
+	}
+}
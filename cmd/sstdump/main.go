@@ -0,0 +1,391 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Sstdump prints the contents of a sorted string table.
+
+Usage:
+
+	sstdump [flags] file.sst
+
+Without section flags, the footer, the index, the meta index and the 
+properties are printed. The flags are:
+
+	-footer      print the footer
+	-index       print the index block
+	-metaindex   print the meta index block
+	-properties  print the properties block
+	-blocks      print the statistics of every data block
+	-entries     print the entries in [-start, -limit)
+	-start key   first key of the entries, the first key of the table if empty
+	-limit key   end of the entries (exclusive), the end of the table if empty
+	-max n       print at most n entries, 0 for no limit
+	-format f    format of the keys and values: escaped, hex or json
+	-mmap        memory map the table
+	-verify      check the whole table and print the problems found, see
+	             table.Verify; the other flags are ignored
+
+In the escaped and json formats, -start and -limit are keys as printed by 
+the escaped format, with or without the double quotes, e.g. "key\x00"; in 
+the hex format they are hex strings. The json format prints one JSON object
+per entry, keys and values are base64 encoded as in encoding/json.
+
+Tables written with another comparator than the bytewise comparator cannot
+be opened.
+*/
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/entuerto/taigaDB/table"
+	"github.com/entuerto/taigaDB/util"
+)
+
+var errFormat = errors.New("sstdump: Unknown format")
+
+type dumper struct {
+	w      io.Writer
+	format string
+	cmp    util.Comparator
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("sstdump", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var (
+		showFooter     = fs.Bool("footer", false, "print the footer")
+		showIndex      = fs.Bool("index", false, "print the index block")
+		showMetaIndex  = fs.Bool("metaindex", false, "print the meta index block")
+		showProperties = fs.Bool("properties", false, "print the properties block")
+		showBlocks     = fs.Bool("blocks", false, "print the statistics of every data block")
+		showEntries    = fs.Bool("entries", false, "print the entries in [-start, -limit)")
+		start          = fs.String("start", "", "first key of the entries")
+		limit          = fs.String("limit", "", "end of the entries (exclusive)")
+		max            = fs.Int("max", 0, "print at most n entries, 0 for no limit")
+		format         = fs.String("format", "escaped", "format of the keys and values: escaped, hex or json")
+		useMmap        = fs.Bool("mmap", false, "memory map the table")
+		verify         = fs.Bool("verify", false, "check the whole table and print the problems found")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: sstdump [flags] file.sst")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("sstdump: Expected one table file")
+	}
+
+	opt := table.DefaultOptions()
+	opt.UseMmap = *useMmap
+
+	if *verify {
+		return verifyTable(fs.Arg(0), stdout)
+	}
+
+	switch *format {
+	case "escaped", "hex", "json":
+	default:
+		return errFormat
+	}
+
+	d := &dumper{w: stdout, format: *format, cmp: opt.Comparator}
+
+	startKey, err := d.parseKey(*start)
+	if err != nil {
+		return err
+	}
+	limitKey, err := d.parseKey(*limit)
+	if err != nil {
+		return err
+	}
+
+	if !*showFooter && !*showIndex && !*showMetaIndex && !*showProperties && !*showBlocks && !*showEntries {
+		*showFooter, *showIndex, *showMetaIndex, *showProperties = true, true, true, true
+	}
+
+	t, err := table.NewReader(fs.Arg(0), opt)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	layout, err := table.ReadLayout(t)
+	if err != nil {
+		return err
+	}
+
+	if *showFooter {
+		d.footer(layout)
+	}
+	if *showIndex {
+		if layout.IndexPartitions != nil {
+			d.index("Index partitions", layout.IndexPartitions)
+		}
+		d.index("Index", layout.BlockIndex)
+	}
+	if *showMetaIndex {
+		d.index("Meta index", layout.MetaIndex)
+	}
+	if *showProperties {
+		d.properties(layout.Properties)
+	}
+	if *showBlocks {
+		if err := d.blocks(t, layout); err != nil {
+			return err
+		}
+	}
+	if *showEntries {
+		if err := d.entries(t, startKey, limitKey, *max); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Prints the problems of the table, fails if there is any.
+func verifyTable(path string, w io.Writer) error {
+	report, err := table.Verify(path, table.DefaultOptions())
+	if err != nil {
+		return err
+	}
+
+	for _, p := range report.Problems {
+		fmt.Fprintln(w, p)
+	}
+	fmt.Fprintf(w, "%d data blocks, %d entries, %d problems\n", report.NumDataBlocks, report.NumEntries, len(report.Problems))
+
+	if !report.OK() {
+		return fmt.Errorf("sstdump: %s is corrupted", path)
+	}
+	return nil
+}
+
+// Parses a key of the command line, nil for an empty key.
+func (self *dumper) parseKey(s string) (table.Slice, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	if self.format == "hex" {
+		key, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("sstdump: Bad key %s: %v", s, err)
+		}
+		return table.Slice(key), nil
+	}
+
+	key, err := unescape(s)
+	if err != nil {
+		return nil, fmt.Errorf("sstdump: Bad key %s: %v", s, err)
+	}
+	return table.Slice(key), nil
+}
+
+// Returns the key or value in the output format. JSON output escapes the 
+// keys outside of the entries.
+func (self *dumper) slice(b []byte) string {
+	if self.format == "hex" {
+		return hex.EncodeToString(b)
+	}
+	return strconv.Quote(string(b))
+}
+
+// Reverses the escaped format, strconv.Quote. The double quotes around s 
+// are optional; only the escapes written by strconv.Quote are known, the 
+// other bytes, double quotes included, are taken as is.
+func unescape(s string) ([]byte, error) {
+	if len(s) >= 2 && s[0] == '"' && s[len(s) - 1] == '"' {
+		s = s[1:len(s) - 1]
+	}
+
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b = append(b, s[i])
+			continue
+		}
+
+		if i++; i == len(s) {
+			return nil, errors.New("trailing backslash")
+		}
+
+		var n int
+		switch c := s[i]; c {
+		case 'a':
+			b = append(b, '\a')
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'v':
+			b = append(b, '\v')
+		case '\\', '"':
+			b = append(b, c)
+		case 'x':
+			n = 2
+		case 'u':
+			n = 4
+		case 'U':
+			n = 8
+		default:
+			return nil, fmt.Errorf("unknown escape \\%c", c)
+		}
+		if n == 0 {
+			continue
+		}
+
+		if i + n >= len(s) {
+			return nil, fmt.Errorf("short escape \\%s", s[i:])
+		}
+		v, err := strconv.ParseUint(s[i + 1:i + 1 + n], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad escape \\%s", s[i:i + 1 + n])
+		}
+
+		switch {
+		case s[i] == 'x':
+			b = append(b, byte(v))
+		case utf8.ValidRune(rune(v)):
+			b = utf8.AppendRune(b, rune(v))
+		default:
+			return nil, fmt.Errorf("bad escape \\%s", s[i:i + 1 + n])
+		}
+		i += n
+	}
+	return b, nil
+}
+
+func (self *dumper) footer(layout *table.Layout) {
+	footer := layout.Footer
+
+	fmt.Fprintln(self.w, "Footer:")
+	fmt.Fprintf(self.w, "  magic number:       %#016x\n", footer.MagicNumber)
+	fmt.Fprintf(self.w, "  format version:     %d\n", footer.Version)
+	fmt.Fprintf(self.w, "  checksum type:      %v\n", footer.ChecksumType)
+	fmt.Fprintf(self.w, "  meta index handle:  offset %d, size %d\n", footer.MetaIndexHandle.Offset, footer.MetaIndexHandle.Size)
+	fmt.Fprintf(self.w, "  index handle:       offset %d, size %d\n", footer.BlockIndexHandle.Offset, footer.BlockIndexHandle.Size)
+	fmt.Fprintf(self.w, "  file size:          %d\n", layout.Size)
+	fmt.Fprintln(self.w)
+}
+
+func (self *dumper) index(name string, idx table.IndexSlice) {
+	fmt.Fprintf(self.w, "%s: %d entries\n", name, len(idx))
+	for _, ie := range idx {
+		fmt.Fprintf(self.w, "  %s: offset %d, size %d\n", self.slice(ie.Key), ie.Handle.Offset, ie.Handle.Size)
+	}
+	fmt.Fprintln(self.w)
+}
+
+func (self *dumper) properties(props *table.Properties) {
+	fmt.Fprintln(self.w, "Properties:")
+	if props == nil {
+		fmt.Fprintln(self.w, "  none")
+		fmt.Fprintln(self.w)
+		return
+	}
+
+	fmt.Fprintf(self.w, "  entries:            %d\n", props.NumEntries)
+	fmt.Fprintf(self.w, "  raw key size:       %d\n", props.RawKeySize)
+	fmt.Fprintf(self.w, "  raw value size:     %d\n", props.RawValueSize)
+	fmt.Fprintf(self.w, "  data blocks:        %d\n", props.NumDataBlocks)
+	fmt.Fprintf(self.w, "  data size:          %d\n", props.DataSize)
+	fmt.Fprintf(self.w, "  index size:         %d\n", props.IndexSize)
+	fmt.Fprintf(self.w, "  index partitions:   %d\n", props.IndexPartitions)
+	fmt.Fprintf(self.w, "  filter size:        %d\n", props.FilterSize)
+	fmt.Fprintf(self.w, "  filter policy:      %s\n", props.FilterPolicyName)
+	fmt.Fprintf(self.w, "  prefix extractor:   %s\n", props.PrefixExtractorName)
+	fmt.Fprintf(self.w, "  comparator:         %s\n", props.ComparatorName)
+	fmt.Fprintf(self.w, "  compression:        %s\n", props.CompressionName)
+	fmt.Fprintf(self.w, "  smallest key:       %s\n", self.slice(props.SmallestKey))
+	fmt.Fprintf(self.w, "  largest key:        %s\n", self.slice(props.LargestKey))
+	fmt.Fprintf(self.w, "  creation time:      %s\n", props.CreationTime.UTC())
+	fmt.Fprintln(self.w)
+}
+
+func (self *dumper) blocks(t table.TableReader, layout *table.Layout) error {
+	fmt.Fprintf(self.w, "Data blocks: %d\n", len(layout.BlockIndex))
+	for i := range layout.BlockIndex {
+		stats, err := table.ReadBlockStats(t, layout.BlockIndex[i].Handle)
+		if err != nil {
+			return fmt.Errorf("sstdump: Data block %d: %v", i, err)
+		}
+		restarts := fmt.Sprintf("%d restarts", stats.NumRestarts)
+		if stats.NumHashBuckets > 0 {
+			restarts += fmt.Sprintf(", %d hash buckets", stats.NumHashBuckets)
+		}
+		fmt.Fprintf(self.w, "  #%d: offset %d, size %d, raw size %d, %v, %d entries, %s, keys [%s, %s]\n",
+			i, stats.Handle.Offset, stats.Handle.Size, stats.RawSize, stats.Compression, 
+			stats.NumEntries, restarts, self.slice(stats.FirstKey), self.slice(stats.LastKey))
+	}
+	fmt.Fprintln(self.w)
+	return nil
+}
+
+func (self *dumper) entries(t table.TableReader, start, limit table.Slice, max int) error {
+	var enc *json.Encoder
+	if self.format == "json" {
+		enc = json.NewEncoder(self.w)
+	} else {
+		fmt.Fprintln(self.w, "Entries:")
+	}
+
+	it := t.Iterator()
+
+	ok := it.SeekToFirst()
+	if start != nil {
+		ok = it.Seek(start)
+	}
+
+	n := 0
+	for ; ok && (max == 0 || n < max); ok = it.Next() {
+		if limit != nil && self.cmp.Compare(it.Key(), limit) >= 0 {
+			break
+		}
+
+		if enc != nil {
+			entry := struct {
+				Key   []byte `json:"key"`
+				Value []byte `json:"value"`
+			}{it.Key(), it.Value()}
+
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(self.w, "  %s => %s\n", self.slice(it.Key()), self.slice(it.Value()))
+		}
+		n++
+	}
+
+	return it.Error()
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testTable = "../../data/h.no-compression.sst"
+
+func TestDump(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if err := run([]string{testTable}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"magic number:       0xdb4775248b80fb57", "Index: 4 entries", `"ford": offset 0, size 4105`, "Properties:\n  none"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestDumpEntries(t *testing.T) {
+	testCases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-entries", "-max", "2"}, "Entries:\n  \"a\" => \"97\"\n  \"aboard\" => \"2\"\n"},
+		{[]string{"-entries", "-format", "hex", "-start", "626162", "-limit", "6261636b"}, "Entries:\n  62616279 => 31\n"},
+		{[]string{"-entries", "-max", "1", "-start", `"aboard"`}, "Entries:\n  \"aboard\" => \"2\"\n"},
+		{[]string{"-entries", "-format", "json", "-max", "1"}, "{\"key\":\"YQ==\",\"value\":\"OTc=\"}\n"},
+	}
+
+	for _, tc := range testCases {
+		var stdout, stderr bytes.Buffer
+
+		if err := run(append(tc.args, testTable), &stdout, &stderr); err != nil {
+			t.Fatalf("%v: %v", tc.args, err)
+		}
+		if stdout.String() != tc.want {
+			t.Errorf("%v: got %q, want %q", tc.args, stdout.String(), tc.want)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	d := &dumper{format: "escaped"}
+
+	for _, key := range []string{"a\"b", "\"", "\"quoted\"", "back\\slash\\", "\x00\a\b\f\n\r\t\v\x7f", "\xff\xfe", "é\u2028\U0001f600\U000e0001"} {
+		quoted := d.slice([]byte(key))
+		for _, s := range []string{quoted, quoted[1:len(quoted) - 1]} {
+			got, err := unescape(s)
+			if err != nil || string(got) != key {
+				t.Errorf("%s: got %q %v, want %q", s, got, err, key)
+			}
+		}
+	}
+
+	// Bytes without escapes are taken as is
+	for s, want := range map[string]string{`a"b`: `a"b`, `"`: `"`, `""`: ``, `\"a"`: `"a"`} {
+		if got, err := unescape(s); err != nil || string(got) != want {
+			t.Errorf("%s: got %q %v, want %q", s, got, err, want)
+		}
+	}
+
+	// Only the escapes of strconv.Quote
+	for _, s := range []string{`\'`, `\000`, `\q`, `a\`, `\x0`, `\xzz`, `\u12`, `\U00110000`, `\ud800`} {
+		if got, err := unescape(s); err == nil {
+			t.Errorf("%s should fail, got %q", s, got)
+		}
+	}
+}
+
+func TestDumpErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-format", "xml", testTable},
+		{"-entries", "-format", "hex", "-start", "zz", testTable},
+		{"missing.sst"},
+	} {
+		var stdout, stderr bytes.Buffer
+
+		if err := run(args, &stdout, &stderr); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if err := run([]string{"-verify", testTable}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if want := "4 data blocks, 1710 entries, 0 problems\n"; stdout.String() != want {
+		t.Errorf("got %q, want %q", stdout.String(), want)
+	}
+
+	data, err := os.ReadFile(testTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[5000] ^= 0xff
+
+	filename := filepath.Join(t.TempDir(), "corrupted.sst")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout.Reset()
+	if err := run([]string{"-verify", filename}, &stdout, &stderr); err == nil {
+		t.Errorf("Verifying a corrupted table should fail")
+	}
+	if !strings.Contains(stdout.String(), "data block at 4110: Table.Block: Block checksum mismatch") {
+		t.Errorf("Should report the corrupted block, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skiplist
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+var (
+	ErrArenaFull = errors.New("SkipList.Arena: not enough space in the arena")
+)
+
+//---------------------------------------------------------------------------------------
+// Arena
+//---------------------------------------------------------------------------------------
+
+const (
+	// Alignment of the nodes, the value word is read and written atomically
+	nodeAlign = 8
+
+	// Offsets of the fields of a node
+	nodeValueOffset   = 0
+	nodeKeyOffset     = 8
+	nodeKeySizeOffset = 12
+	nodeTowerOffset   = 16
+)
+
+/*
+arena is a fixed size buffer holding the nodes, keys and values of an
+ArenaSkipList. Offsets in the arena stand for pointers, the offset 0 is
+never allocated and stands for nil.
+
+Node Structure:
+
+    +--------------------------------+
+    | Value (uint64)                 |  Offset of the value in the high 32 bits,
+    +--------------------------------+  size in the low 32 bits.
+    | Key offset (uint32)            |
+    +--------------------------------+
+    | Key size (uint32)              |
+    +--------------------------------+
+    | Tower (uint32[height])         |  Offsets of the next nodes, one per level.
+    +--------------------------------+
+
+The tower of a node is cut to its height, most nodes only use a few bytes of it.
+*/
+type arena struct {
+	buf []byte
+	// Number of bytes in use
+	n uint32
+}
+
+func newArena(size int) *arena {
+	// Offsets are 32 bits
+	n := uint64(size)
+	if n > math.MaxUint32 {
+		n = math.MaxUint32
+	}
+	// Allocated as words for the alignment of the values
+	words := make([]uint64, n / nodeAlign + 1)
+	return &arena{
+		buf: unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), int(n)),
+		n: 1,
+	}
+}
+
+// Returns the offset of size bytes aligned to align.
+func (self *arena) alloc(size, align uint32) uint32 {
+	offset := (self.n + align - 1) &^ (align - 1)
+	self.n = offset + size
+	return offset
+}
+
+// Returns whether size bytes, aligned to align, are available.
+func (self *arena) fits(size uint64, align uint32) bool {
+	offset := (uint64(self.n) + uint64(align) - 1) &^ (uint64(align) - 1)
+	return offset <= uint64(len(self.buf)) && size <= uint64(len(self.buf)) - offset
+}
+
+func (self *arena) putBytes(b []byte) uint32 {
+	offset := self.alloc(uint32(len(b)), 1)
+	copy(self.buf[offset:], b)
+	return offset
+}
+
+func (self *arena) bytes(offset, size uint32) []byte {
+	return self.buf[offset:offset + size:offset + size]
+}
+
+func (self *arena) loadUint32(offset uint32) uint32 {
+	return atomic.LoadUint32((*uint32)(unsafe.Pointer(&self.buf[offset])))
+}
+
+func (self *arena) storeUint32(offset uint32, v uint32) {
+	atomic.StoreUint32((*uint32)(unsafe.Pointer(&self.buf[offset])), v)
+}
+
+func (self *arena) loadUint64(offset uint32) uint64 {
+	return atomic.LoadUint64((*uint64)(unsafe.Pointer(&self.buf[offset])))
+}
+
+func (self *arena) storeUint64(offset uint32, v uint64) {
+	atomic.StoreUint64((*uint64)(unsafe.Pointer(&self.buf[offset])), v)
+}
+
+// Size of a node with height levels
+func nodeSize(height int) uint32 {
+	return uint32(nodeTowerOffset + height * 4)
+}
+
+//---------------------------------------------------------------------------------------
+// Arena SkipList
+//---------------------------------------------------------------------------------------
+
+/*
+ArenaSkipList is a skip list of []byte keys and values stored in an arena
+allocated once, the nodes are not seen by the garbage collector. As the
+ConcurrentSkipList, readers and iterators never lock and writers are serialized.
+
+The arena is never grown, Put fails with ErrArenaFull once it is full. A
+memtable uses ArenaSize to decide when to flush the list. Replaced values are
+not freed.
+*/
+type ArenaSkipList struct {
+	arena *arena
+	// Offset of the head node
+	head uint32
+	// Number of levels in use, at least 1
+	level  atomic.Int32
+	length atomic.Uint64
+
+	cmp CompareFunc[[]byte]
+
+	// Serializes the writers
+	mu sync.Mutex
+
+	nodes nodes[[]byte, []byte, uint32]
+}
+
+// Create a new ArenaSkipList object with an arena of arenaSize bytes,
+// that will use "cmp" for comparing keys.
+func NewArena(arenaSize int, cmp CompareFunc[[]byte]) *ArenaSkipList {
+	if arenaSize < 0 {
+		panic("skiplist: negative arena size")
+	}
+
+	a := newArena(arenaSize)
+	if !a.fits(uint64(nodeSize(defaultMaxLevel)), nodeAlign) {
+		panic("skiplist: arena too small for the head node")
+	}
+
+	list := &ArenaSkipList{
+		arena: a,
+		head: a.alloc(nodeSize(defaultMaxLevel), nodeAlign),
+		cmp: cmp,
+	}
+	list.level.Store(1)
+	list.nodes = nodes[[]byte, []byte, uint32]{list}
+
+	return list
+}
+
+// Returns a random number of levels in [1, defaultMaxLevel].
+func (self *ArenaSkipList) randomLevel() (n int) {
+	for n = 1; rand.Float64() < defaultP && n < defaultMaxLevel; n++ {
+	}
+	return
+}
+
+// The length of the skip list
+func (self *ArenaSkipList) Len() uint {
+	return uint(self.length.Load())
+}
+
+// The number of bytes of the arena in use
+func (self *ArenaSkipList) ArenaSize() int {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	return int(self.arena.n)
+}
+
+// The size of the arena
+func (self *ArenaSkipList) ArenaCapacity() int {
+	return len(self.arena.buf)
+}
+
+func (self *ArenaSkipList) next(node uint32, level int) uint32 {
+	return self.arena.loadUint32(node + nodeTowerOffset + uint32(level) * 4)
+}
+
+func (self *ArenaSkipList) setNext(node uint32, level int, next uint32) {
+	self.arena.storeUint32(node + nodeTowerOffset + uint32(level) * 4, next)
+}
+
+func (self *ArenaSkipList) key(node uint32) []byte {
+	return self.arena.bytes(self.arena.loadUint32(node + nodeKeyOffset), self.arena.loadUint32(node + nodeKeySizeOffset))
+}
+
+func (self *ArenaSkipList) value(node uint32) []byte {
+	v := self.arena.loadUint64(node + nodeValueOffset)
+	return self.arena.bytes(uint32(v >> 32), uint32(v))
+}
+
+func (self *ArenaSkipList) setValue(node uint32, value []byte) {
+	offset := self.arena.putBytes(value)
+	self.arena.storeUint64(node + nodeValueOffset, uint64(offset) << 32 | uint64(len(value)))
+}
+
+// Returns the value associated with key. The value is in the arena and
+// must not be modified.
+func (self *ArenaSkipList) Get(key []byte) ([]byte, bool) {
+	if candidate := self.nodes.findGreaterOrEqual(key, nil); candidate != 0 && self.cmp(self.key(candidate), key) == 0 {
+		return self.value(candidate), true
+	}
+	return nil, false
+}
+
+// Put key into the list, the value of an equal key is replaced. The key and
+// value are copied into the arena, ErrArenaFull is returned if they do not
+// fit.
+func (self *ArenaSkipList) Put(key, value []byte) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	var update [defaultMaxLevel]uint32
+
+	candidate := self.nodes.findGreaterOrEqual(key, update[:])
+	if candidate != 0 && self.cmp(self.key(candidate), key) == 0 {
+		if !self.arena.fits(uint64(len(value)), 1) {
+			return ErrArenaFull
+		}
+		self.setValue(candidate, value)
+		return nil
+	}
+
+	newLevel := self.randomLevel()
+	if !self.arena.fits(uint64(nodeSize(newLevel)) + nodeAlign + uint64(len(key)) + uint64(len(value)), 1) {
+		return ErrArenaFull
+	}
+
+	node := self.arena.alloc(nodeSize(newLevel), nodeAlign)
+	self.arena.storeUint32(node + nodeKeyOffset, self.arena.putBytes(key))
+	self.arena.storeUint32(node + nodeKeySizeOffset, uint32(len(key)))
+	self.setValue(node, value)
+
+	if level := int(self.level.Load()); newLevel > level {
+		for i := level; i < newLevel; i++ {
+			update[i] = self.head
+		}
+		self.level.Store(int32(newLevel))
+	}
+
+	for i := 0; i < newLevel; i++ {
+		self.setNext(node, i, self.next(update[i], i))
+		self.setNext(update[i], i, node)
+	}
+
+	self.length.Add(1)
+	return nil
+}
+
+// True if an entry that compares equal to key is in the list
+func (self *ArenaSkipList) Contains(key []byte) bool {
+	_, ok := self.Get(key)
+	return ok
+}
+
+func (self *ArenaSkipList) GreaterOrEqual(key []byte) *KV[[]byte, []byte] {
+	if candidate := self.nodes.findGreaterOrEqual(key, nil); candidate != 0 {
+		return &KV[[]byte, []byte]{self.key(candidate), self.value(candidate)}
+	}
+	return nil
+}
+
+// Returns an iterator. The keys and values are in the arena and must not be
+// modified.
+func (self *ArenaSkipList) Iterator() Iterator[[]byte, []byte] {
+	return self.nodes.iterator()
+}
+
+func (self *ArenaSkipList) Min() *KV[[]byte, []byte] {
+	if min := self.next(self.head, 0); min != 0 {
+		return &KV[[]byte, []byte]{self.key(min), self.value(min)}
+	}
+	return nil
+}
+
+func (self *ArenaSkipList) Max() *KV[[]byte, []byte] {
+	if max := self.nodes.findLast(); max != self.head {
+		return &KV[[]byte, []byte]{self.key(max), self.value(max)}
+	}
+	return nil
+}
+
+// The nodes of the list to the search and the iterators
+
+func (self *ArenaSkipList) headNode() uint32 {
+	return self.head
+}
+
+func (self *ArenaSkipList) numLevels() int {
+	return int(self.level.Load())
+}
+
+func (self *ArenaSkipList) nextNode(n uint32, level int) uint32 {
+	return self.next(n, level)
+}
+
+func (self *ArenaSkipList) nodeKey(n uint32) []byte {
+	return self.key(n)
+}
+
+func (self *ArenaSkipList) nodeEntry(n uint32) ([]byte, []byte) {
+	return self.key(n), self.value(n)
+}
+
+func (self *ArenaSkipList) compare(a, b []byte) int {
+	return self.cmp(a, b)
+}
@@ -0,0 +1,141 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skiplist
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Returns a put function failing the test when the arena is full.
+func arenaPut(t *testing.T, s *ArenaSkipList) func(key, value []byte) {
+	return func(key, value []byte) {
+		if err := s.Put(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestArenaSkipList(t *testing.T) {
+	s := NewArena(1 << 20, bytes.Compare)
+	if s.ArenaSize() != int(nodeSize(defaultMaxLevel)) + nodeAlign {
+		t.Errorf("Arena should only hold the head, got %d bytes", s.ArenaSize())
+	}
+
+	checkByteList(t, s, arenaPut(t, s))
+
+	size := s.ArenaSize()
+	arenaPut(t, s)(listKey(10), []byte("replaced again"))
+	if s.ArenaSize() != size + len("replaced again") {
+		t.Errorf("Replacing a value should only use its size, got %d bytes", s.ArenaSize() - size)
+	}
+}
+
+func TestArenaSkipListSize(t *testing.T) {
+	for size, want := range map[int]string{
+		-1: "skiplist: negative arena size",
+		0: "skiplist: arena too small for the head node",
+		int(nodeSize(defaultMaxLevel)): "skiplist: arena too small for the head node",
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != want {
+					t.Errorf("Arena of %d bytes should panic with %q, got %v", size, want, r)
+				}
+			}()
+			NewArena(size, bytes.Compare)
+		}()
+	}
+
+	// Sizes of entries wrapping around in 32 bits
+	a := newArena(1 << 10)
+	if a.fits(math.MaxUint32 + 16, 1) || a.fits(math.MaxUint64 - 16, nodeAlign) {
+		t.Error("Entries larger than the arena should not fit")
+	}
+}
+
+func TestArenaSkipListCopies(t *testing.T) {
+	s := NewArena(1 << 10, bytes.Compare)
+
+	key, value := []byte("key"), []byte("value")
+	if err := s.Put(key, value); err != nil {
+		t.Fatal(err)
+	}
+	key[0], value[0] = 'x', 'x'
+
+	if v, ok := s.Get([]byte("key")); !ok || string(v) != "value" {
+		t.Errorf("Put should copy the key and value, got %s %v", v, ok)
+	}
+
+	// Empty keys and values
+	if err := s.Put(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := s.Get([]byte{}); !ok || len(v) != 0 {
+		t.Errorf("Empty key should be found with an empty value, got %q %v", v, ok)
+	}
+	if kv := s.Min(); kv == nil || len(kv.Key) != 0 {
+		t.Errorf("Empty key should be the first, got %v", kv)
+	}
+}
+
+func TestArenaSkipListFull(t *testing.T) {
+	const arenaSize = 4 << 10
+
+	s := NewArena(arenaSize, bytes.Compare)
+
+	var n int
+	for ; ; n++ {
+		size := s.ArenaSize()
+		if err := s.Put(listKey(n), listValue(n)); err != nil {
+			if err != ErrArenaFull {
+				t.Fatalf("Should be ErrArenaFull, got %v", err)
+			}
+			if s.ArenaSize() != size {
+				t.Errorf("A failed Put should not use the arena, got %d bytes", s.ArenaSize() - size)
+			}
+			break
+		}
+		if s.ArenaSize() > arenaSize {
+			t.Fatalf("Arena should use at most %d bytes, got %d", arenaSize, s.ArenaSize())
+		}
+	}
+	if n == 0 || s.Len() != uint(n) {
+		t.Errorf("length should be %d returned %d", n, s.Len())
+	}
+	if s.ArenaCapacity() != arenaSize {
+		t.Errorf("Arena capacity should be %d, got %d", arenaSize, s.ArenaCapacity())
+	}
+
+	for i := 0; i < n; i++ {
+		if v, ok := s.Get(listKey(i)); !ok || !bytes.Equal(v, listValue(i)) {
+			t.Fatalf("Value of %s should be %s, got %s %v", listKey(i), listValue(i), v, ok)
+		}
+	}
+	if err := s.Put(listKey(0), make([]byte, arenaSize)); err != ErrArenaFull {
+		t.Errorf("Replacing with a value larger than the arena should be ErrArenaFull, got %v", err)
+	}
+}
+
+func TestArenaSkipListReadersWriter(t *testing.T) {
+	s := NewArena(1 << 20, bytes.Compare)
+	checkReadersWriter(t, s, arenaPut(t, s))
+}
+
+func BenchmarkArenaPut(b *testing.B) {
+	s := NewArena(b.N * 128 + 1 << 10, bytes.Compare)
+	keys := make([][]byte, b.N)
+	for i := range keys {
+		keys[i] = listKey(rand.Int())
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Put(keys[i], keys[i])
+	}
+}
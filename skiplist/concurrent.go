@@ -0,0 +1,193 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skiplist
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+//---------------------------------------------------------------------------------------
+// Concurrent SkipList
+//---------------------------------------------------------------------------------------
+
+/*
+ConcurrentSkipList is a skip list safe for concurrent use, as the memtable of
+LevelDB. The forward pointers are atomic: readers and iterators never lock, and
+see the entries inserted before they read the pointers. Writers are serialized
+by a mutex.
+
+A node is published by linking it at level 0 after its forward pointers are set,
+then at the upper levels. Entries are never removed, iterators stay valid while
+entries are inserted.
+*/
+type ConcurrentSkipList[K, V any] struct {
+	head *cnode[K, V]
+	// Number of levels in use, at least 1
+	level  atomic.Int32
+	length atomic.Uint64
+
+	cmp CompareFunc[K]
+
+	// Serializes the writers
+	mu sync.Mutex
+
+	nodes nodes[K, V, *cnode[K, V]]
+}
+
+type cnode[K, V any] struct {
+	// Replaced as a whole when the value of the key is replaced
+	kv atomic.Pointer[KV[K, V]]
+
+	forward []atomic.Pointer[cnode[K, V]]
+}
+
+func (self *cnode[K, V]) next(level int) *cnode[K, V] {
+	return self.forward[level].Load()
+}
+
+func (self *cnode[K, V]) key() K {
+	return self.kv.Load().Key
+}
+
+// Create a new ConcurrentSkipList object that will use "cmp" for comparing
+// keys.
+func NewConcurrent[K, V any](cmp CompareFunc[K]) *ConcurrentSkipList[K, V] {
+	list := &ConcurrentSkipList[K, V]{
+		head: &cnode[K, V]{
+			forward: make([]atomic.Pointer[cnode[K, V]], defaultMaxLevel),
+		},
+		cmp: cmp,
+	}
+	list.head.kv.Store(new(KV[K, V]))
+	list.level.Store(1)
+	list.nodes = nodes[K, V, *cnode[K, V]]{list}
+
+	return list
+}
+
+// Returns a random number of levels in [1, defaultMaxLevel].
+func (self *ConcurrentSkipList[K, V]) randomLevel() (n int) {
+	for n = 1; rand.Float64() < defaultP && n < defaultMaxLevel; n++ {
+	}
+	return
+}
+
+// The length of the skip list
+func (self *ConcurrentSkipList[K, V]) Len() uint {
+	return uint(self.length.Load())
+}
+
+// Returns the value associated with key.
+func (self *ConcurrentSkipList[K, V]) Get(key K) (V, bool) {
+	if candidate := self.nodes.findGreaterOrEqual(key, nil); candidate != nil {
+		if kv := candidate.kv.Load(); self.cmp(kv.Key, key) == 0 {
+			return kv.Value, true
+		}
+	}
+
+	var value V
+	return value, false
+}
+
+// Put key into the list, the value of an equal key is replaced. The key is
+// not copied, a []byte key must not be modified afterwards.
+func (self *ConcurrentSkipList[K, V]) Put(key K, value V) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	var update [defaultMaxLevel]*cnode[K, V]
+
+	candidate := self.nodes.findGreaterOrEqual(key, update[:])
+	if candidate != nil && self.cmp(candidate.key(), key) == 0 {
+		candidate.kv.Store(&KV[K, V]{candidate.key(), value})
+		return
+	}
+
+	newLevel := self.randomLevel()
+	if level := int(self.level.Load()); newLevel > level {
+		for i := level; i < newLevel; i++ {
+			update[i] = self.head
+		}
+		// Readers seeing the new level before the node is linked find nil
+		// pointers in the head and move down to the next level.
+		self.level.Store(int32(newLevel))
+	}
+
+	node := &cnode[K, V]{
+		forward: make([]atomic.Pointer[cnode[K, V]], newLevel),
+	}
+	node.kv.Store(&KV[K, V]{key, value})
+
+	for i := 0; i < newLevel; i++ {
+		node.forward[i].Store(update[i].next(i))
+		update[i].forward[i].Store(node)
+	}
+
+	self.length.Add(1)
+}
+
+// True if an entry that compares equal to key is in the list
+func (self *ConcurrentSkipList[K, V]) Contains(key K) bool {
+	_, ok := self.Get(key)
+	return ok
+}
+
+func (self *ConcurrentSkipList[K, V]) GreaterOrEqual(key K) *KV[K, V] {
+	if candidate := self.nodes.findGreaterOrEqual(key, nil); candidate != nil {
+		return candidate.kv.Load()
+	}
+	return nil
+}
+
+// Returns an iterator. An iterator is not safe for concurrent use, many 
+// iterators can be used concurrently with writers.
+func (self *ConcurrentSkipList[K, V]) Iterator() Iterator[K, V] {
+	return self.nodes.iterator()
+}
+
+func (self *ConcurrentSkipList[K, V]) Min() *KV[K, V] {
+	if min := self.head.next(0); min != nil {
+		return min.kv.Load()
+	}
+	return nil
+}
+
+func (self *ConcurrentSkipList[K, V]) Max() *KV[K, V] {
+	if max := self.nodes.findLast(); max != self.head {
+		return max.kv.Load()
+	}
+	return nil
+}
+
+// The nodes of the list to the search and the iterators
+
+func (self *ConcurrentSkipList[K, V]) headNode() *cnode[K, V] {
+	return self.head
+}
+
+func (self *ConcurrentSkipList[K, V]) numLevels() int {
+	return int(self.level.Load())
+}
+
+func (self *ConcurrentSkipList[K, V]) nextNode(n *cnode[K, V], level int) *cnode[K, V] {
+	return n.next(level)
+}
+
+func (self *ConcurrentSkipList[K, V]) nodeKey(n *cnode[K, V]) K {
+	return n.key()
+}
+
+// The entry is loaded once, Key and Value of an iterator stay consistent
+// while the value is replaced.
+func (self *ConcurrentSkipList[K, V]) nodeEntry(n *cnode[K, V]) (K, V) {
+	kv := n.kv.Load()
+	return kv.Key, kv.Value
+}
+
+func (self *ConcurrentSkipList[K, V]) compare(a, b K) int {
+	return self.cmp(a, b)
+}
@@ -0,0 +1,76 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skiplist
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentSkipList(t *testing.T) {
+	s := NewConcurrent[[]byte, []byte](bytes.Compare)
+	checkByteList(t, s, s.Put)
+}
+
+func TestConcurrentReadersWriter(t *testing.T) {
+	s := NewConcurrent[[]byte, []byte](bytes.Compare)
+	checkReadersWriter(t, s, s.Put)
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	const (
+		n       = 1000
+		writers = 4
+	)
+
+	s := NewConcurrent[int, int](compare)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				s.Put(i * writers + w, w)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if s.Len() != n * writers {
+		t.Errorf("length should be %d returned %d", n * writers, s.Len())
+	}
+
+	i := s.Iterator()
+	for k := 0; i.Next(); k++ {
+		if i.Key() != k || i.Value() != k % writers {
+			t.Fatalf("Should be %d: %d, got %d: %d", k, k % writers, i.Key(), i.Value())
+		}
+	}
+}
+
+// An iterator sees the entries inserted after its position.
+func TestConcurrentIteratorInserts(t *testing.T) {
+	s := NewConcurrent[int, int](compare)
+	for i := 0; i < 100; i += 10 {
+		s.Put(i, i)
+	}
+
+	i := s.Iterator()
+	if !i.Seek(50) {
+		t.Fatal("Seek(50) should succeed")
+	}
+
+	s.Put(55, 55)
+	s.Put(45, 45)
+
+	if !i.Next() || i.Key() != 55 {
+		t.Errorf("Next should be the inserted 55, got %v", i.Key())
+	}
+	if !i.Prev() || i.Key() != 50 || !i.Prev() || i.Key() != 45 {
+		t.Errorf("Prev should go back to the inserted 45, got %v", i.Key())
+	}
+}
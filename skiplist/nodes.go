@@ -0,0 +1,173 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package skiplist
+
+//---------------------------------------------------------------------------------------
+// Search
+//---------------------------------------------------------------------------------------
+
+// The nodes of a skip list, as seen by the search and the iterators. N is 
+// the handle of a node, a pointer or an offset in an arena. The zero N is 
+// no node. The ConcurrentSkipList and the ArenaSkipList share the search 
+// and the iterators, SkipList keeps its own to avoid the interface calls.
+type nodeList[K, V any, N comparable] interface {
+	// The node before the first entry, with a forward pointer per level
+	headNode() N
+	// Number of levels in use
+	numLevels() int
+	// The node after n at level
+	nextNode(n N, level int) N
+	nodeKey(n N) K
+	// The key and value of the node, read together
+	nodeEntry(n N) (K, V)
+	compare(a, b K) int
+}
+
+// Search and iterators shared by the skip lists.
+type nodes[K, V any, N comparable] struct {
+	list nodeList[K, V, N]
+}
+
+// Returns the first node with a key >= key, the zero N if there is none. If
+// update is not nil, it is populated with the last node before key at each
+// level, and must have a length of at least the number of levels.
+func (self nodes[K, V, N]) findGreaterOrEqual(key K, update []N) N {
+	var none N
+
+	current := self.list.headNode()
+	for i := self.list.numLevels() - 1; i >= 0; i-- {
+		for next := self.list.nextNode(current, i); next != none && self.list.compare(self.list.nodeKey(next), key) < 0; next = self.list.nextNode(current, i) {
+			current = next
+		}
+		if update != nil {
+			update[i] = current
+		}
+	}
+	return self.list.nextNode(current, 0)
+}
+
+// Returns the last node with a key < key, or head if there is no such 
+// node.
+func (self nodes[K, V, N]) findLessThan(key K) N {
+	var none N
+
+	current := self.list.headNode()
+	for i := self.list.numLevels() - 1; i >= 0; i-- {
+		for next := self.list.nextNode(current, i); next != none && self.list.compare(self.list.nodeKey(next), key) < 0; next = self.list.nextNode(current, i) {
+			current = next
+		}
+	}
+	return current
+}
+
+// Returns the last node, or head if the list is empty.
+func (self nodes[K, V, N]) findLast() N {
+	var none N
+
+	current := self.list.headNode()
+	for i := self.list.numLevels() - 1; i >= 0; i-- {
+		for next := self.list.nextNode(current, i); next != none; next = self.list.nextNode(current, i) {
+			current = next
+		}
+	}
+	return current
+}
+
+// Returns an iterator positioned before the first entry.
+func (self nodes[K, V, N]) iterator() Iterator[K, V] {
+	return &nodeIter[K, V, N]{
+		current: self.list.headNode(),
+		nodes: self,
+	}
+}
+
+//---------------------------------------------------------------------------------------
+// Iterator
+//---------------------------------------------------------------------------------------
+
+// Iterator over a nodeList. Key and Value are read when the iterator moves
+// to an entry.
+type nodeIter[K, V any, N comparable] struct {
+	// The head of the list before the first entry, the zero N after the 
+	// last entry
+	current N
+	// Entry of the current node when it was reached
+	key     K
+	value   V
+
+	nodes   nodes[K, V, N]
+}
+
+func (self nodeIter[K, V, N]) Valid() bool {
+	var none N
+	return self.current != none && self.current != self.nodes.list.headNode()
+}
+
+func (self nodeIter[K, V, N]) Key() K {
+	return self.key
+}
+
+func (self nodeIter[K, V, N]) Value() V {
+	return self.value
+}
+
+func (self *nodeIter[K, V, N]) Next() bool {
+	var none N
+	if self.current == none {
+		return false
+	}
+	return self.set(self.nodes.list.nextNode(self.current, 0))
+}
+
+func (self *nodeIter[K, V, N]) Prev() bool {
+	var none N
+
+	switch self.current {
+	case self.nodes.list.headNode():
+		return false
+	case none:
+		return self.SeekToLast()
+	}
+
+	// Instead of using explicit "prev" links, we just search for the
+	// last node that falls before key.
+	return self.set(self.nodes.findLessThan(self.key))
+}
+
+func (self *nodeIter[K, V, N]) Seek(key K) bool {
+	return self.set(self.nodes.findGreaterOrEqual(key, nil))
+}
+
+func (self *nodeIter[K, V, N]) SeekForPrev(key K) bool {
+	var none N
+
+	list := self.nodes.list
+	if node := self.nodes.findGreaterOrEqual(key, nil); node != none && list.compare(list.nodeKey(node), key) == 0 {
+		return self.set(node)
+	}
+	return self.set(self.nodes.findLessThan(key))
+}
+
+func (self *nodeIter[K, V, N]) SeekToFirst() bool {
+	return self.set(self.nodes.list.nextNode(self.nodes.list.headNode(), 0))
+}
+
+func (self *nodeIter[K, V, N]) SeekToLast() bool {
+	return self.set(self.nodes.findLast())
+}
+
+// Moves to node and reads its entry, Key and Value are then stable while
+// the value of the key is replaced.
+func (self *nodeIter[K, V, N]) set(node N) bool {
+	var key K
+	var value V
+
+	self.current = node
+	self.key, self.value = key, value
+	if self.Valid() {
+		self.key, self.value = self.nodes.list.nodeEntry(node)
+	}
+	return self.Valid()
+}
@@ -18,19 +18,44 @@ const (
 	defaultMaxLevel = 32
 )
 
-type LessFunc func(l, r interface{}) bool
+// Three-way comparison of keys. Returns value:
+//   < 0 iff "a" < "b",
+//   == 0 iff "a" == "b",
+//   > 0 iff "a" > "b"
+type CompareFunc[K any] func(a, b K) int
+
+// Reports whether "a" < "b".
+type LessFunc[K any] func(a, b K) bool
+
+// Returns a CompareFunc ordering the keys as less. Two keys are equal when
+// neither is less than the other.
+func FromLess[K any](less LessFunc[K]) CompareFunc[K] {
+	return func(a, b K) int {
+		switch {
+		case less(a, b):
+			return -1
+		case less(b, a):
+			return 1
+		}
+		return 0
+	}
+}
 
 // Interface that you can use to implement an iterator that iterates 
-// through a skip list
-type Iterator interface {
+// through a skip list.
+//
+// A new iterator is positioned before the first entry, calling Next moves 
+// it to the first entry. Once exhausted, moving in the opposite direction 
+// moves it back to the first or last entry.
+type Iterator[K, V any] interface {
 	// Is positioned at a valid node
 	Valid() bool
 
 	// Key returns the current key.
-	Key() interface{}
+	Key() K
 	
 	// Value returns the current value.
-	Value() interface{}
+	Value() V
 
 	// Advances to the next position and returns true if valid node
 	Next() bool
@@ -39,86 +64,113 @@ type Iterator interface {
 	Prev() bool
 
 	// Advance to the first entry with a key >= target
-	Seek(key interface{}) bool
+	Seek(key K) bool
+
+	// Position at the last entry with a key <= target
+	SeekForPrev(key K) bool
+
+	// Position at the first entry in the list
+	SeekToFirst() bool
+
+	// Position at the last entry in the list
+	SeekToLast() bool
 }
 
-type iter struct {
-	current *node
-	list    *SkipList
+type iter[K, V any] struct {
+	// The head of the list before the first entry, nil after the last 
+	// entry
+	current *node[K, V]
+	list    *SkipList[K, V]
 }
 
-func (self iter) Valid() bool {
-	return self.current != nil
+func (self iter[K, V]) Valid() bool {
+	return self.current != nil && self.current != self.list.head
 }
 
-func (self iter) Key() interface{} {
+func (self iter[K, V]) Key() K {
 	if self.Valid() {
 		return self.current.Key
 	}
-	return nil
+	var key K
+	return key
 }
 
-func (self iter) Value() interface{} {
+func (self iter[K, V]) Value() V {
 	if self.Valid() {
 		return self.current.Value
 	}
-	return nil
+	var value V
+	return value
 }
 
-func (self *iter) Next() bool {
-	if next := self.current.next(); next != nil {
-		self.current = next
-		return true
+func (self *iter[K, V]) Next() bool {
+	if self.current == nil {
+		return false
 	}
-	return false
+
+	self.current = self.current.next()
+	return self.current != nil
 }
 
-func (self *iter) Prev() bool {
-	// Instead of using explicit "prev" links, we just search for the
-	// last node that falls before key.
-	if !self.Valid() {
+func (self *iter[K, V]) Prev() bool {
+	switch self.current {
+	case self.list.head:
 		return false
+	case nil:
+		return self.SeekToLast()
 	}
 
-//	node := self.list.FindLessThan(self.current.key)
-//	return node != self.list.head
-	return false
+	// Instead of using explicit "prev" links, we just search for the
+	// last node that falls before key.
+	self.current = self.list.findLessThan(self.current.Key)
+	return self.Valid()
 }
 
-func (self *iter) Seek(key interface{}) bool {
-	current := self.current
+func (self *iter[K, V]) Seek(key K) bool {
+	self.current = self.list.findNode(self.list.head, nil, key)
+	return self.current != nil
+}
 
-	// If the existing iterator outside of the known key range, we should set the
-	// position back to the beginning of the list.
-	if current == nil {
-		current = self.list.head
+func (self *iter[K, V]) SeekForPrev(key K) bool {
+	if node := self.list.findNode(self.list.head, nil, key); node != nil && self.list.cmp(node.Key, key) == 0 {
+		self.current = node
+		return true
 	}
 
-	self.current = self.list.findNode(current, nil, key)
+	self.current = self.list.findLessThan(key)
+	return self.Valid()
+}
 
+func (self *iter[K, V]) SeekToFirst() bool {
+	self.current = self.list.head.next()
 	return self.current != nil
 }
 
+func (self *iter[K, V]) SeekToLast() bool {
+	self.current = self.list.findLast()
+	return self.Valid()
+}
+
 //---------------------------------------------------------------------------------------
 //
 //---------------------------------------------------------------------------------------
 
-type KV struct {
-	Key     interface{}
-	Value   interface{}
+type KV[K, V any] struct {
+	Key     K
+	Value   V
 }
 
 //---------------------------------------------------------------------------------------
 //
 //---------------------------------------------------------------------------------------
 
-type node struct {
-	*KV
+type node[K, V any] struct {
+	*KV[K, V]
 
-	forward []*node
+	forward []*node[K, V]
 }
 
-func (self *node) next() *node {
+func (self *node[K, V]) next() *node[K, V] {
 	if len(self.forward) == 0 {
 		return nil
 	}
@@ -130,36 +182,38 @@ func (self *node) next() *node {
 //
 //---------------------------------------------------------------------------------------
 
-type SkipList struct {
-	head     *node
+type SkipList[K, V any] struct {
+	head     *node[K, V]
 	length   uint
 
 	P        float64
 	MaxLevel int
-	less     LessFunc
+	cmp      CompareFunc[K]
 }
 
-// Create a new SkipList object that will use "cmp" for comparing keys
-func New(less LessFunc) *SkipList {
-	return &SkipList{
-		head: &node{
-			KV: new(KV),
-			forward: []*node{nil},
+// Create a new SkipList object that will use "cmp" for comparing keys. Keys
+// are equal when cmp returns 0, any key type can be used. For []byte keys,
+// bytes.Compare is a CompareFunc.
+func New[K, V any](cmp CompareFunc[K]) *SkipList[K, V] {
+	return &SkipList[K, V]{
+		head: &node[K, V]{
+			KV: new(KV[K, V]),
+			forward: []*node[K, V]{nil},
 		},
 		length: 0,
 		P: defaultP,
 		MaxLevel: defaultMaxLevel,
-		less: less,
+		cmp: cmp,
 	}
 }
 
-func (self *SkipList) level() int {
+func (self *SkipList[K, V]) level() int {
 	// Returns the level-1 of the skip list, used for slices indices.
 	// The level of an empty skip list is 1.
 	return len(self.head.forward) - 1
 }
 
-func (self *SkipList) randomLevel() (n int) {
+func (self *SkipList[K, V]) randomLevel() (n int) {
 	// Returns a random level in the range [0, s.level()+1] been at most
 	// equal to s.maxLevel-1. Used for slices indices.
 	for n = 0; rand.Float64() < self.P && n < self.MaxLevel - 1; n++ {
@@ -168,26 +222,28 @@ func (self *SkipList) randomLevel() (n int) {
 }
 
 // The length of the skip list
-func (self *SkipList) Len() uint {
+func (self *SkipList[K, V]) Len() uint {
 	return self.length
 }
 
 // Returns the value associated with key. 
-func (self *SkipList) Get(key interface{}) (interface{}, bool) {
+func (self *SkipList[K, V]) Get(key K) (V, bool) {
 	
-	if candidate := self.findNode(self.head, nil, key); candidate != nil && candidate.Key == key {
+	if candidate := self.findNode(self.head, nil, key); candidate != nil && self.cmp(candidate.Key, key) == 0 {
 		return candidate.Value, true
 	}
 
-	return nil, false
+	var value V
+	return value, false
 }
 
-// Put key into the list, existing key is replaced
-func (self *SkipList) Put(key, value interface{}) {
-	update := make([]*node, self.level() + 1)
+// Put key into the list, the value of an equal key is replaced. The key is 
+// not copied, a []byte key must not be modified afterwards.
+func (self *SkipList[K, V]) Put(key K, value V) {
+	update := make([]*node[K, V], self.level() + 1)
 	candidate := self.findNode(self.head, update, key)
 
-	if candidate != nil && candidate.Key == key {
+	if candidate != nil && self.cmp(candidate.Key, key) == 0 {
 		candidate.Value = value
 		return
 	}
@@ -201,9 +257,9 @@ func (self *SkipList) Put(key, value interface{}) {
 		}
 	}
 
-	node := &node{
-		KV: &KV{key, value},
-		forward: make([]*node, newLevel + 1), 
+	node := &node[K, V]{
+		KV: &KV[K, V]{key, value},
+		forward: make([]*node[K, V], newLevel + 1), 
 	}
 	for i := 0; i <= newLevel; i++ {
 		node.forward[i] = update[i].forward[i]
@@ -214,12 +270,12 @@ func (self *SkipList) Put(key, value interface{}) {
 }
 
 // True if an entry that compares equal to key is in the list
-func (self *SkipList) Contains(key interface{}) bool {
+func (self *SkipList[K, V]) Contains(key K) bool {
 	_, ok := self.Get(key)
 	return ok
 }
 
-func (self *SkipList) GreaterOrEqual(key interface{}) *KV {
+func (self *SkipList[K, V]) GreaterOrEqual(key K) *KV[K, V] {
 
 	if candidate := self.findNode(self.head, nil, key); candidate != nil {
 		return candidate.KV
@@ -228,23 +284,19 @@ func (self *SkipList) GreaterOrEqual(key interface{}) *KV {
 }
 
 // Returns an iterator
-func (self *SkipList) Iterator() Iterator {
-	return &iter{
+func (self *SkipList[K, V]) Iterator() Iterator[K, V] {
+	return &iter[K, V]{
 		current: self.head,
 		list: self,
 	}
 }
 
 // Removes the key from the list.
-func (self *SkipList) Remove(key interface{}) (*KV, bool) {
-	if key == nil {
-		return nil, false
-	}
-
-	update := make([]*node, self.level() + 1)
+func (self *SkipList[K, V]) Remove(key K) (*KV[K, V], bool) {
+	update := make([]*node[K, V], self.level() + 1)
 	candidate := self.findNode(self.head, update, key)
 
-	if candidate == nil || candidate.Key != key {
+	if candidate == nil || self.cmp(candidate.Key, key) != 0 {
 		return nil, false
 	}
 
@@ -260,25 +312,41 @@ func (self *SkipList) Remove(key interface{}) (*KV, bool) {
 	return candidate.KV, true
 }
 
-func (self *SkipList) Min() *KV {
+func (self *SkipList[K, V]) Min() *KV[K, V] {
 	if min := self.head.next(); min != nil {
 		return min.KV
 	}
 	return nil
 }
 
-func (self *SkipList) Max() *KV {
+func (self *SkipList[K, V]) Max() *KV[K, V] {
+	if max := self.findLast(); max != self.head {
+		return max.KV
+	}
+	return nil
+}
+
+// Returns the last node with a key < key, or head if there is no such 
+// node.
+func (self *SkipList[K, V]) findLessThan(key K) *node[K, V] {
 	current := self.head
 	for i := self.level(); i >= 0; i-- {
-		for current.forward[i] != nil {
+		for current.forward[i] != nil && self.cmp(current.forward[i].Key, key) < 0 {
 			current = current.forward[i]
 		}
 	}
+	return current
+}
 
-	if current == self.head {
-		return nil
+// Returns the last node, or head if the list is empty.
+func (self *SkipList[K, V]) findLast() *node[K, V] {
+	current := self.head
+	for i := self.level(); i >= 0; i-- {
+		for current.forward[i] != nil {
+			current = current.forward[i]
+		}
 	}
-	return current.KV
+	return current
 }
 
 // findNode populates update with nodes that constitute the path to the
@@ -288,11 +356,11 @@ func (self *SkipList) Max() *KV {
 // (the candidate node will still be returned). If update is not nil, but it 
 // doesn't have enough height (levels) for all the nodes in the path, 
 // findNode will panic.
-func (self *SkipList) findNode(current *node, update []*node, key interface{}) *node {
+func (self *SkipList[K, V]) findNode(current *node[K, V], update []*node[K, V], key K) *node[K, V] {
 	depth := len(current.forward) - 1
 
 	for i := depth; i >= 0; i-- {
-		for current.forward[i] != nil && self.less(current.forward[i].Key, key) {
+		for current.forward[i] != nil && self.cmp(current.forward[i].Key, key) < 0 {
 			current = current.forward[i]
 		}
 		if update != nil {
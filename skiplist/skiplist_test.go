@@ -5,16 +5,26 @@
 package skiplist
 
 import (
-	_ "math/rand"
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
 	"testing"
 )
 
-func less(l, r interface{}) bool {
-	return l.(int) < r.(int)
+func compare(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
 }
 
 func TestLen(t *testing.T) {
-	s := New(less)
+	s := New[int, int](compare)
 	if s.Len() != 0 {
 		t.Error("length should be 0")
 	}
@@ -35,7 +45,7 @@ func TestLen(t *testing.T) {
 }
 
 func TestLevel(t *testing.T) {
-	s := New(less)
+	s := New[int, int](compare)
 	if s.level() != 0 {
 		t.Error("level should be 0")
 	}
@@ -43,7 +53,7 @@ func TestLevel(t *testing.T) {
 
 /*
 func TestRandomLevel(t *testing.T) {
-	s := New(less)
+	s := New[int, int](compare)
 	s.MaxLevel = 32
 	if v := s.randomLevel(); v != 2 {
 		t.Errorf("random level should be 2 returned %d", v)
@@ -52,27 +62,27 @@ func TestRandomLevel(t *testing.T) {
 */
 
 func TestEmptyNodeNext(t *testing.T) {
-	n := new(node)
+	n := new(node[int, int])
 	if next := n.next(); next != nil {
 		t.Errorf("Next() should be nil for an empty node.")
 	}
 }
 
 func TestGet(t *testing.T) {
-	s := New(less)
+	s := New[int, int](compare)
 	s.Put(0, 0)
 
 	if value, ok := s.Get(0); !(value == 0 && ok) {
 		t.Errorf("%v, %v instead of %v, %v", value, ok, 0, true)
 	}
 
-	if value, ok := s.Get(100); value != nil || ok {
-		t.Errorf("%v, %v instead of %v, %v", value, ok, nil, false)
+	if value, ok := s.Get(100); value != 0 || ok {
+		t.Errorf("%v, %v instead of %v, %v", value, ok, 0, false)
 	}
 }
 
 func TestGreaterOrEqual(t *testing.T) {
-	s := New(less)
+	s := New[int, int](compare)
 
 	if kv := s.GreaterOrEqual(5); kv != nil  {
 		t.Errorf("s.GreaterOrEqual(5) should have returned nil and nil for an empty map, not %v and %v.", kv.Key, kv.Value)
@@ -91,14 +101,14 @@ func TestGreaterOrEqual(t *testing.T) {
 	}
 }
 
-func (s *SkipList) equals(t *testing.T, key, wanted int) {
+func equals(t *testing.T, s *SkipList[int, int], key, wanted int) {
 	if got, _ := s.Get(key); got != wanted {
 		t.Errorf("For key %v wanted value %v, got %v.", key, wanted, got)
 	}
 }
 
 func TestPut(t *testing.T) {
-	s := New(less)
+	s := New[int, int](compare)
 	if l := s.Len(); l != 0 {
 		t.Errorf("Len is not 0, it is %v", l)
 	}
@@ -108,16 +118,16 @@ func TestPut(t *testing.T) {
 	if l := s.Len(); l != 2 {
 		t.Errorf("Len is not 2, it is %v", l)
 	}
-	s.equals(t, 0, 0)
+	equals(t, s, 0, 0)
 	if t.Failed() {
 		t.Errorf("header.Next() after s.Set(0, 0) and s.Set(1, 1): %v.", s.head.next())
 	}
-	s.equals(t, 1, 1)
+	equals(t, s, 1, 1)
 
 }
 
 func TestChange(t *testing.T) {
-	s := New(less)
+	s := New[int, int](compare)
 	s.Put(0, 0)
 	s.Put(1, 1)
 	s.Put(2, 2)
@@ -134,7 +144,7 @@ func TestChange(t *testing.T) {
 }
 
 func TestRemove(t *testing.T) {
-	s := New(less)
+	s := New[int, int](compare)
 	for i := 0; i < 10; i++ {
 		s.Put(i, i)
 	}
@@ -155,7 +165,7 @@ func TestRemove(t *testing.T) {
 }
 
 func TestIteration(t *testing.T) {
-	s := New(less)
+	s := New[int, int](compare)
 	for i := 0; i < 20; i++ {
 		s.Put(i, i)
 	}
@@ -167,7 +177,7 @@ func TestIteration(t *testing.T) {
 
 	for i.Next() {
 		seen++
-		lastKey = i.Key().(int)
+		lastKey = i.Key()
 		if i.Key() != i.Value() {
 			t.Errorf("Wrong value for key %v: %v.", i.Key(), i.Value())
 		}
@@ -176,27 +186,27 @@ func TestIteration(t *testing.T) {
 	if seen != s.Len() {
 		t.Errorf("Not all the items in s where iterated through (seen %d, should have seen %d). Last one seen was %d.", seen, s.Len(), lastKey)
 	}
-/*
+
+	lastKey = int(s.Len())
 	for i.Prev() {
 		if i.Key() != i.Value() {
 			t.Errorf("Wrong value for key %v: %v.", i.Key(), i.Value())
 		}
 
-		if i.Key().(int) >= lastKey {
+		if i.Key() >= lastKey {
 			t.Errorf("Expected key to descend but ascended from %v to %v.", lastKey, i.Key())
 		}
 
-		lastKey = i.Key().(int)
+		lastKey = i.Key()
 	}
 
 	if lastKey != 0 {
 		t.Errorf("Expected to count back to zero, but stopped at key %v.", lastKey)
 	}
-*/
 }
 
 func TestIterationSeek(t *testing.T) {
-	s := New(less)
+	s := New[int, int](compare)
 	for i := 0; i < 20; i++ {
 		s.Put(i, i)
 	}
@@ -204,6 +214,395 @@ func TestIterationSeek(t *testing.T) {
 	i := s.Iterator()
 
 	if !i.Seek(5) {
-		t.Errorf("Could not seek to key of value 5 got %v.", i.Key)
+		t.Errorf("Could not seek to key of value 5 got %v.", i.Key())
+	}
+
+	// Seek moves backwards too
+	if !i.Seek(2) || i.Key() != 2 {
+		t.Errorf("Could not seek back to key of value 2 got %v.", i.Key())
+	}
+	if i.Seek(20) || i.Valid() {
+		t.Errorf("Seek past the last key should not be valid, got %v.", i.Key())
+	}
+	if !i.Prev() || i.Key() != 19 {
+		t.Errorf("Prev after the last key should be 19, got %v.", i.Key())
+	}
+}
+
+func TestIteratorStart(t *testing.T) {
+	s := New[int, int](compare)
+	s.Put(1, 10)
+
+	i := s.Iterator()
+	if i.Valid() || i.Key() != 0 || i.Value() != 0 {
+		t.Errorf("New iterator should not be valid, got %v: %v", i.Key(), i.Value())
+	}
+	if i.Prev() {
+		t.Error("Prev before the first entry should fail")
+	}
+	if !i.Next() || i.Key() != 1 {
+		t.Errorf("Next should move to the first entry, got %v", i.Key())
+	}
+	if i.Prev() || i.Valid() {
+		t.Error("Prev from the first entry should not reach the head")
+	}
+	if !i.Next() || i.Key() != 1 {
+		t.Errorf("Next should move back to the first entry, got %v", i.Key())
+	}
+	if i.Next() || i.Next() {
+		t.Error("Next after the last entry should fail")
+	}
+
+	empty := New[int, int](compare).Iterator()
+	if empty.SeekToFirst() || empty.SeekToLast() || empty.Seek(0) || empty.SeekForPrev(0) {
+		t.Error("Iterators of an empty list should not be valid")
+	}
+}
+
+func TestIteratorSeekToFirstLast(t *testing.T) {
+	s := New[int, int](compare)
+	for i := 0; i < 100; i += 10 {
+		s.Put(i, i)
+	}
+
+	i := s.Iterator()
+	if !i.SeekToLast() || i.Key() != 90 {
+		t.Errorf("SeekToLast should be 90, got %v", i.Key())
+	}
+
+	n := 90
+	for i.Prev() {
+		n -= 10
+		if i.Key() != n {
+			t.Fatalf("Should be %d, got %v", n, i.Key())
+		}
+	}
+	if n != 0 {
+		t.Errorf("Should iterate back to 0, stopped at %d", n)
+	}
+
+	if !i.SeekToFirst() || i.Key() != 0 {
+		t.Errorf("SeekToFirst should be 0, got %v", i.Key())
+	}
+}
+
+func TestIteratorSeekForPrev(t *testing.T) {
+	s := New[int, int](compare)
+	for i := 0; i < 100; i += 10 {
+		s.Put(i, i)
+	}
+
+	testCases := []struct {
+		key   int
+		want  int
+		valid bool
+	}{
+		{-1, 0, false},
+		{0, 0, true},
+		{5, 0, true},
+		{10, 10, true},
+		{55, 50, true},
+		{90, 90, true},
+		{1000, 90, true},
+	}
+
+	i := s.Iterator()
+	for _, tc := range testCases {
+		if ok := i.SeekForPrev(tc.key); ok != tc.valid || ok != i.Valid() || (ok && i.Key() != tc.want) {
+			t.Errorf("SeekForPrev(%d) should be %d %v, got %d %v", tc.key, tc.want, tc.valid, i.Key(), ok)
+		}
+	}
+
+	// Before the first entry, Next moves to the first entry
+	i.SeekForPrev(-1)
+	if !i.Next() || i.Key() != 0 {
+		t.Errorf("Next should move to the first entry, got %v", i.Key())
+	}
+}
+
+func TestStringKeys(t *testing.T) {
+	type value struct {
+		n int
+	}
+
+	s := New[string, value](func(a, b string) int {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	})
+
+	for _, key := range []string{"c", "a", "b"} {
+		s.Put(key, value{int(s.Len()) + 1})
+	}
+
+	if min, max := s.Min(), s.Max(); min.Key != "a" || max.Key != "c" {
+		t.Errorf("Min and max should be a and c, got %v and %v", min.Key, max.Key)
+	}
+	if v, ok := s.Get("b"); !ok || v.n != 3 {
+		t.Errorf("Value of b should be 3, got %v %v", v, ok)
+	}
+	if v, ok := s.Get("d"); ok || v != (value{}) {
+		t.Errorf("d should not be present, got %v", v)
+	}
+}
+
+func TestBytesKeys(t *testing.T) {
+	s := New[[]byte, int](bytes.Compare)
+
+	for i := 0; i < 10; i++ {
+		s.Put([]byte{'k', byte('0' + i)}, i)
+	}
+	// Equal keys in other slices replace the values
+	for i := 0; i < 10; i += 2 {
+		s.Put([]byte{'k', byte('0' + i)}, i * 10)
+	}
+	if s.Len() != 10 {
+		t.Errorf("length should be 10 returned %d", s.Len())
+	}
+
+	for i := 0; i < 10; i++ {
+		want := i
+		if i % 2 == 0 {
+			want = i * 10
+		}
+		if v, ok := s.Get([]byte{'k', byte('0' + i)}); !ok || v != want {
+			t.Errorf("Value of k%d should be %d, got %v %v", i, want, v, ok)
+		}
+	}
+
+	if kv, ok := s.Remove([]byte("k3")); !ok || kv.Value != 3 {
+		t.Errorf("Remove k3 should return 3, got %v %v", kv, ok)
+	}
+	if s.Contains([]byte("k3")) {
+		t.Error("k3 should not be present")
+	}
+	if kv, ok := s.Remove([]byte("k")); ok || kv != nil {
+		t.Errorf("Removing a non-existent key should fail, got %v", kv)
+	}
+}
+
+func TestFromLess(t *testing.T) {
+	// Keys equal under less but not ==
+	s := New[string, int](FromLess(func(a, b string) bool {
+		return strings.ToLower(a) < strings.ToLower(b)
+	}))
+
+	s.Put("Key", 1)
+	s.Put("key", 2)
+	s.Put("other", 3)
+
+	if s.Len() != 2 {
+		t.Errorf("length should be 2 returned %d", s.Len())
+	}
+	if v, ok := s.Get("KEY"); !ok || v != 2 {
+		t.Errorf("Value of KEY should be 2, got %v %v", v, ok)
+	}
+	if _, ok := s.Remove("OTHER"); !ok {
+		t.Error("OTHER should be removed")
+	}
+
+	cmp := FromLess(func(a, b int) bool { return a < b })
+	if cmp(1, 2) >= 0 || cmp(2, 1) <= 0 || cmp(2, 2) != 0 {
+		t.Error("FromLess should order the keys as less")
+	}
+}
+
+func TestByteList(t *testing.T) {
+	s := New[[]byte, []byte](bytes.Compare)
+	checkByteList(t, s, s.Put)
+}
+
+//---------------------------------------------------------------------------------------
+// Skip lists of []byte keys and values
+//---------------------------------------------------------------------------------------
+
+// The methods shared by the skip lists, with []byte keys and values.
+type byteList interface {
+	Len() uint
+	Get(key []byte) ([]byte, bool)
+	Contains(key []byte) bool
+	GreaterOrEqual(key []byte) *KV[[]byte, []byte]
+	Iterator() Iterator[[]byte, []byte]
+	Min() *KV[[]byte, []byte]
+	Max() *KV[[]byte, []byte]
+}
+
+func listKey(i int) []byte {
+	return []byte(fmt.Sprintf("key%06d", i))
+}
+
+func listValue(i int) []byte {
+	return []byte(fmt.Sprintf("value%d", i))
+}
+
+// Puts the even keys of [0, 200), replaces the value of key 10, and checks
+// the lookups and the iterators.
+func checkByteList(t *testing.T, s byteList, put func(key, value []byte)) {
+	if s.Len() != 0 || s.Min() != nil || s.Max() != nil {
+		t.Error("empty list should have no entries")
+	}
+
+	for _, i := range rand.Perm(100) {
+		put(listKey(i * 2), listValue(i))
+	}
+	put(listKey(10), []byte("replaced"))
+
+	if s.Len() != 100 {
+		t.Errorf("length should be 100 returned %d", s.Len())
+	}
+	if v, ok := s.Get(listKey(10)); !ok || string(v) != "replaced" {
+		t.Errorf("Value of %s should be replaced, got %s %v", listKey(10), v, ok)
+	}
+	if v, ok := s.Get(listKey(12)); !ok || !bytes.Equal(v, listValue(6)) {
+		t.Errorf("Value of %s should be %s, got %s %v", listKey(12), listValue(6), v, ok)
+	}
+	if _, ok := s.Get(listKey(11)); ok || s.Contains(listKey(11)) {
+		t.Errorf("%s should not be present", listKey(11))
+	}
+	if kv := s.GreaterOrEqual(listKey(11)); kv == nil || !bytes.Equal(kv.Key, listKey(12)) {
+		t.Errorf("GreaterOrEqual(%s) should be %s, got %v", listKey(11), listKey(12), kv)
+	}
+	if min, max := s.Min(), s.Max(); !bytes.Equal(min.Key, listKey(0)) || !bytes.Equal(max.Key, listKey(198)) {
+		t.Errorf("Min and max should be %s and %s, got %s and %s", listKey(0), listKey(198), min.Key, max.Key)
+	}
+
+	i := s.Iterator()
+	if i.Valid() || i.Prev() {
+		t.Error("New iterator should not be valid")
+	}
+
+	n := 0
+	for i.Next() {
+		if !bytes.Equal(i.Key(), listKey(n * 2)) {
+			t.Fatalf("Should be %s, got %s", listKey(n * 2), i.Key())
+		}
+		n++
+	}
+	if n != 100 || i.Key() != nil {
+		t.Errorf("Should iterate over 100 entries, got %d", n)
+	}
+	for i.Prev() {
+		n--
+		if !bytes.Equal(i.Key(), listKey(n * 2)) {
+			t.Fatalf("Should be %s, got %s", listKey(n * 2), i.Key())
+		}
+	}
+	if n != 0 {
+		t.Errorf("Should iterate back to 0, stopped at %d", n)
+	}
+
+	if !i.SeekForPrev(listKey(11)) || !bytes.Equal(i.Key(), listKey(10)) || string(i.Value()) != "replaced" {
+		t.Errorf("SeekForPrev(%s) should be %s: replaced, got %s: %s", listKey(11), listKey(10), i.Key(), i.Value())
+	}
+	if !i.Seek(listKey(11)) || !bytes.Equal(i.Key(), listKey(12)) {
+		t.Errorf("Seek(%s) should be %s, got %s", listKey(11), listKey(12), i.Key())
+	}
+	if !i.SeekToLast() || !bytes.Equal(i.Key(), listKey(198)) || !i.SeekToFirst() || !bytes.Equal(i.Key(), listKey(0)) {
+		t.Error("SeekToLast and SeekToFirst should be the last and first keys")
+	}
+}
+
+// Readers check the entries are sorted and the values match the keys while
+// put inserts n keys, then replaces the values of half of them.
+func checkReadersWriter(t *testing.T, s byteList, put func(key, value []byte)) {
+	const (
+		n       = 5000
+		readers = 4
+	)
+
+	// The values end with the number of their key
+	value := func(i int, prefix string) []byte {
+		return []byte(fmt.Sprintf("%s%06d", prefix, i))
+	}
+	matches := func(key, value []byte) bool {
+		return bytes.HasSuffix(value, key[len("key"):])
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+
+			rnd := rand.New(rand.NewSource(int64(r)))
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				var last []byte
+				iter := s.Iterator()
+				for iter.Next() {
+					if last != nil && bytes.Compare(last, iter.Key()) >= 0 {
+						t.Errorf("Keys should ascend, got %s after %s", iter.Key(), last)
+						return
+					}
+					if !matches(iter.Key(), iter.Value()) {
+						t.Errorf("Value of %s should match the key, got %s", iter.Key(), iter.Value())
+						return
+					}
+					last = iter.Key()
+				}
+
+				i := rnd.Intn(n)
+				if v, ok := s.Get(listKey(i)); ok && !matches(listKey(i), v) {
+					t.Errorf("Value of %s should match the key, got %s", listKey(i), v)
+					return
+				}
+			}
+		}(r)
+	}
+
+	for _, i := range rand.Perm(n) {
+		put(listKey(i), value(i, "value"))
+	}
+	for i := 0; i < n; i += 2 {
+		put(listKey(i), value(i, "replaced"))
+	}
+	close(done)
+	wg.Wait()
+
+	if s.Len() != n {
+		t.Errorf("length should be %d returned %d", n, s.Len())
+	}
+	for i := 0; i < n; i++ {
+		want := value(i, "value")
+		if i % 2 == 0 {
+			want = value(i, "replaced")
+		}
+		if v, ok := s.Get(listKey(i)); !ok || !bytes.Equal(v, want) {
+			t.Fatalf("Value of %s should be %s, got %s %v", listKey(i), want, v, ok)
+		}
+	}
+}
+
+func BenchmarkPut(b *testing.B) {
+	b.ReportAllocs()
+
+	s := New[int, int](compare)
+	for i := 0; i < b.N; i++ {
+		s.Put(i, i)
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	s := New[int, int](compare)
+	for i := 0; i < 10000; i++ {
+		s.Put(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.Get(i % 10000)
 	}
 }
@@ -17,6 +17,14 @@ const (
 	//  echo http://code.google.com/p/leveldb/ | sha1sum
 	TableMagicNumber = uint64(0xdb4775248b80fb57)
 
+	// Magic number of tables with a versioned footer, the same as RocksDB
+	// block based tables.
+	VersionedTableMagicNumber = uint64(0x88e241b785f4cff7)
+
+	// Format version written in versioned footers. Blocks compressed with 
+	// LZ4 or Zstd start with the decompressed size.
+	FormatVersion = uint32(2)
+
 	// 1-byte type + 32-bit crc
 	BlockTrailerSize = 5
 
@@ -25,6 +33,10 @@ const (
 
 	// Encoded length of a Footer.
 	FooterEncodedLength = 2 * MaxEncodedLength + 8
+
+	// Encoded length of a versioned Footer: 1-byte checksum type + handles 
+	// + 32-bit version + magic.
+	VersionedFooterEncodedLength = 1 + 2 * MaxEncodedLength + 4 + 8
 )
 
 //---------------------------------------------------------------------------------------
@@ -90,6 +102,12 @@ func (self *BlockHandle) Decode(data Slice) (int, error) {
 type Footer struct {
 	MetaIndexHandle  *BlockHandle
 	BlockIndexHandle *BlockHandle
+
+	// Checksum type and format version, only stored in versioned footers.
+	// A version 0 footer uses the LevelDB format with CRC32C checksums.
+	ChecksumType ChecksumType
+	Version      uint32
+
 	MagicNumber uint64
 }
 
@@ -97,27 +115,55 @@ func NewFooter(metaIndexHandle, blockIndexHandle *BlockHandle) *Footer {
 	return &Footer{
 		MetaIndexHandle: metaIndexHandle,
 		BlockIndexHandle: blockIndexHandle,
+		ChecksumType: CRC32CChecksum,
 		MagicNumber: TableMagicNumber,
 	}
 }
 
+// Creates a footer with a checksum type and the current format version.
+func NewVersionedFooter(metaIndexHandle, blockIndexHandle *BlockHandle, checksum ChecksumType) *Footer {
+	return &Footer{
+		MetaIndexHandle: metaIndexHandle,
+		BlockIndexHandle: blockIndexHandle,
+		ChecksumType: checksum,
+		Version: FormatVersion,
+		MagicNumber: VersionedTableMagicNumber,
+	}
+}
+
 func (self Footer) String() string {
-	return fmt.Sprintf("Footer { MetaIndexHandle: %v, BlockIndexHandle: %v, MagicNumber: %d}", 
+	return fmt.Sprintf("Footer { MetaIndexHandle: %v, BlockIndexHandle: %v, ChecksumType: %d, Version: %d, MagicNumber: %d}", 
 		              self.MetaIndexHandle, 
 		              self.BlockIndexHandle,
+		              self.ChecksumType,
+		              self.Version,
 		              self.MagicNumber)
 }
 
+// Returns the number of bytes used to encode the footer.
+func (self Footer) EncodedLength() int {
+	if self.Version == 0 {
+		return FooterEncodedLength
+	}
+	return VersionedFooterEncodedLength
+}
+
 // Encodes to data and returns the number of bytes written. If the buffer 
 // is too small, it will panic.
 func (self Footer) Encode(data Slice) (int, error) {
-	if len(data) < FooterEncodedLength {
+	length := self.EncodedLength()
+	if len(data) < length {
 		return 0, ErrEncodeFooterBuffer
 	}
 
 	var pos int
 
-	if metaSize,  _ := self.MetaIndexHandle.Encode(data); metaSize != 0 {
+	if self.Version != 0 {
+		data[0] = self.ChecksumType.id()
+		pos++
+	}
+
+	if metaSize,  _ := self.MetaIndexHandle.Encode(data[pos:]); metaSize != 0 {
 		pos += metaSize
 	}
 
@@ -125,15 +171,51 @@ func (self Footer) Encode(data Slice) (int, error) {
 		pos += blockSize
 	}
 
-	binary.LittleEndian.PutUint64(data[40:], TableMagicNumber)
-	return FooterEncodedLength, nil
+	if self.Version == 0 {
+		binary.LittleEndian.PutUint64(data[40:], TableMagicNumber)
+		return FooterEncodedLength, nil
+	}
+
+	for i := pos; i < length - 12; i++ {
+		data[i] = 0
+	}
+	binary.LittleEndian.PutUint32(data[length - 12:], self.Version)
+	binary.LittleEndian.PutUint64(data[length - 8:], VersionedTableMagicNumber)
+	return length, nil
 }
 
-// Decodes from data and returns the number of bytes read
+// Decodes the footer at the end of data and returns the number of bytes read
 func (self *Footer) Decode(data Slice) (int, error) {
 	var n, m int
 	var err error
 
+	if len(data) < FooterEncodedLength {
+		return 0, ErrDecodeSmallBuffer
+	}
+
+	self.MagicNumber  = binary.LittleEndian.Uint64(data[len(data) - 8:])
+	self.ChecksumType = CRC32CChecksum
+	self.Version      = 0
+
+	length := FooterEncodedLength
+	if self.MagicNumber == VersionedTableMagicNumber {
+		if len(data) < VersionedFooterEncodedLength {
+			return 0, ErrDecodeSmallBuffer
+		}
+		length = VersionedFooterEncodedLength
+	}
+	data = data[len(data) - length:]
+
+	if length == VersionedFooterEncodedLength {
+		self.ChecksumType = checksumTypeOf(data[0])
+		self.Version      = binary.LittleEndian.Uint32(data[length - 12:])
+		// Version 0 is the LevelDB footer, without the versioned magic
+		if self.Version == 0 {
+			return 0, ErrTableMagicNumber
+		}
+		data = data[1:]
+	}
+
 	if self.MetaIndexHandle == nil {
 		self.MetaIndexHandle = NewHandle(0, 0)
 	}
@@ -150,8 +232,23 @@ func (self *Footer) Decode(data Slice) (int, error) {
 		return m, err
 	}
 
-	self.MagicNumber = binary.LittleEndian.Uint64(data[40:])
-	return FooterEncodedLength, nil
+	return length, nil
+}
+
+//---------------------------------------------------------------------------------------
+// Block Trailer
+//---------------------------------------------------------------------------------------
+
+// Returns the checksum stored in the block trailer. The data covers the block
+// contents and the compression type.
+func blockChecksum(checksum ChecksumType, data []byte) uint32 {
+	switch checksum {
+	case CRC32CChecksum:
+		return util.Checksum32(data)
+	case XXHash64Checksum:
+		return uint32(util.XXHash64(data, 0))
+	}
+	return 0
 }
 
 //---------------------------------------------------------------------------------------
@@ -163,12 +260,75 @@ Blocks have one or many key/value entries followed by a block trailer structure.
 */
 type Block []byte
 
+// Decodes the end of the block: the number of restart points and the 
+// buckets of the hash index, nil if the block has no hash index. Returns 0
+// restart points if the block is too small for its restart array and hash
+// index.
+func (self Block) footer() (int, Slice) {
+	if len(self) < 4 {
+		return 0, nil
+	}
+
+	packed := binary.LittleEndian.Uint32(self[len(self) - 4:])
+	end := len(self) - 4
+
+	var buckets Slice
+	if packed & blockHashIndexFlag != 0 {
+		if end < 2 {
+			return 0, nil
+		}
+		numBuckets := int(binary.LittleEndian.Uint16(self[end - 2:]))
+		if numBuckets == 0 || numBuckets + 2 > end {
+			return 0, nil
+		}
+		end -= 2 + numBuckets
+		buckets = Slice(self[end:end + numBuckets])
+	}
+
+	n := uint64(packed &^ blockHashIndexFlag)
+	if n * 4 > uint64(end) {
+		return 0, nil
+	}
+	return int(n), buckets
+}
+
+// Returns the number of restart points, or 0 if the block is too small for
+// its restart array.
 func (self Block) NumberOfRestarts() int {
-	return int(binary.LittleEndian.Uint32(self[len(self) - 4:]))
+	n, _ := self.footer()
+	return n
 }
 
+// Returns the number of buckets of the hash index, or 0 if the block has 
+// no hash index.
+func (self Block) NumberOfHashBuckets() int {
+	_, buckets := self.footer()
+	return len(buckets)
+}
+
+// Returns the offset of the restart array, the end of the block entries.
 func (self Block) RestartStartOffset() int {
-	return len(self) - (1 + int(self.NumberOfRestarts())) * 4
+	if len(self) < 4 {
+		return 0
+	}
+
+	n, buckets := self.footer()
+	if buckets != nil {
+		return len(self) - 4 - (2 + len(buckets)) - n * 4
+	}
+	return len(self) - (1 + n) * 4
+}
+
+// Checks the block has room for its restart array, and at least one 
+// restart point.
+func (self Block) checkRestarts() error {
+	if len(self) < 4 {
+		return &CorruptionError{Offset: 0, Err: ErrBlockRestarts}
+	}
+	if self.NumberOfRestarts() == 0 {
+		return &CorruptionError{Offset: len(self) - 4, Err: ErrBlockRestarts}
+	}
+	return nil
 }
 
 func (self Block) Restarts() []uint32 {
@@ -186,37 +346,15 @@ func (self Block) Restarts() []uint32 {
 	return restarts
 }
 
-func (self Block) Search(key Slice, cmp util.Comparator) *BlockEntry {
-	numRestarts := self.NumberOfRestarts()
-	if numRestarts == 0  {
-		return nil
-	}
-
-	restarts := self.Restarts()
-
-	var entry BlockEntry
-	// Search uses binary search to find and return the smallest index
-	pos := sort.Search(numRestarts, func(i int) bool {
-		readBlockEntry(self[restarts[i]:], &entry)
-
-		return cmp.Compare(entry.Key, key) > 0 	
-	})
-
-	if pos < numRestarts {
-		// Goto previous restart because we found Key > restart(key)
-		pos--
-		
-		iter := NewEntryIterator(self[restarts[pos]:]) 
-		for e, ok := iter.Next(); ok; { 
-			if cmp.Compare(e.Key, key) == 0 {
-				return e
-			}
-			e, ok = iter.Next()
-		}
-
+// Search returns the entry with the given key, or nil if the block does
+// not contain the key. The hash index of the block is used if present. The
+// error is set if the block is malformed.
+func (self Block) Search(key Slice, cmp util.Comparator) (*BlockEntry, error) {
+	iter := newBlockIterator(self, cmp)
+	if iter.seekExact(key) {
+		return &iter.entry, nil
 	}
-
-	return nil
+	return nil, iter.Error()
 }
 
 // Prints block entries for debuging porposes
@@ -251,19 +389,37 @@ func (self BlockEntry) String() string {
 		              self.Value)
 }
 
-// Helper function to decode the next block entry. 
-// It returns the truncated block slice that was read.
-func readBlockEntry(b Block, entry *BlockEntry) Block {
-	var n0, n1, n2 int
-	entry.Shared,   n0 = binary.Uvarint(b)
-	entry.Unshared, n1 = binary.Uvarint(b[n0:])
-	entry.ValueLen, n2 = binary.Uvarint(b[n0 + n1:])
-	
+// Decodes the entry at the start of b and returns the rest of b. The key 
+// of entry must hold the key of the previous entry, the shared prefix is 
+// taken from it. Returns ErrBlockEntryCorruption if the entry does not fit 
+// in b.
+func readBlockEntry(b Block, entry *BlockEntry) (Block, error) {
+	shared, n0 := binary.Uvarint(b)
+	if n0 <= 0 {
+		return nil, ErrBlockEntryCorruption
+	}
+	unshared, n1 := binary.Uvarint(b[n0:])
+	if n1 <= 0 {
+		return nil, ErrBlockEntryCorruption
+	}
+	valueLen, n2 := binary.Uvarint(b[n0 + n1:])
+	if n2 <= 0 {
+		return nil, ErrBlockEntryCorruption
+	}
+
 	n := n0 + n1 + n2
-	entry.Key   = Slice(append(entry.Key[:int(entry.Shared)], b[n:n + int(entry.Unshared)]...))
-	entry.Value = Slice(b[n + int(entry.Unshared):n + int(entry.Unshared + entry.ValueLen)])
+	if shared > uint64(len(entry.Key)) || unshared > uint64(len(b) - n) || valueLen > uint64(len(b) - n) - unshared {
+		return nil, ErrBlockEntryCorruption
+	}
+
+	entry.Shared   = shared
+	entry.Unshared = unshared
+	entry.ValueLen = valueLen
+	
+	entry.Key   = Slice(append(entry.Key[:int(shared)], b[n:n + int(unshared)]...))
+	entry.Value = Slice(b[n + int(unshared):n + int(unshared + valueLen)])
 
-	return b[n + int(entry.Unshared + entry.ValueLen):]
+	return b[n + int(unshared + valueLen):], nil
 }
 
 //---------------------------------------------------------------------------------------
@@ -285,30 +441,32 @@ func (self IndexEntry) String() string {
 
 // Helper function to decode the index entries. 
 // It returns an index slice.
-func decodeIndexEntries(b Block) IndexSlice {
-	numRestarts := b.NumberOfRestarts()
-	idxSlice := make(IndexSlice, numRestarts)
+func decodeIndexEntries(b Block) (IndexSlice, error) {
+	var idxSlice IndexSlice
 
-	for i := 0; i < numRestarts; i++ {
-		shared,   n0 := binary.Uvarint(b)
-		unshared, n1 := binary.Uvarint(b[n0:])
-		valueLen, n2 := binary.Uvarint(b[n0 + n1:])
-	
+	iter := NewEntryIterator(b) 
+	for entry, ok := iter.Next(); ok; { 
 		var ie = new(IndexEntry)
 
-		n := n0 + n1 + n2
-		ie.Key =  Slice(append(ie.Key[:int(shared)], b[n:n + int(unshared)]...))
-		value  := Slice(b[n + int(unshared):n + int(unshared + valueLen)])
-		b = b[n + int(unshared + valueLen):]
-	
-		ie.Handle.Decode(value)
-	
-		idxSlice[i] = ie
+		ie.Key = Slice(append([]byte(nil), entry.Key...))
+		if _, err := ie.Handle.Decode(entry.Value); err != nil {
+			return nil, &CorruptionError{Offset: iter.Offset(), Err: err}
+		}
+
+		idxSlice = append(idxSlice, ie)
+
+		entry, ok = iter.Next()
 	}
 
-	return idxSlice
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return idxSlice, nil
 }
 
+// IndexSlice is the sorted entries of an index block. As a sort.Interface
+// it orders the keys bytewise, SortFunc and Search use the comparator of 
+// the table.
 type IndexSlice []*IndexEntry
 
 func (self IndexSlice) Len() int { 
@@ -328,7 +486,24 @@ func (self IndexSlice) Sort() {
 	sort.Sort(self) 
 }
 
-// Search returns the result of applying Search to the receiver and x. 
-func (self IndexSlice) Search(key Slice) int { 
-	return sort.Search(len(self), func(i int) bool { return string(self[i].Key) >= string(key) })
+// SortFunc sorts the entries by key under cmp. A nil cmp orders the keys 
+// bytewise.
+func (self IndexSlice) SortFunc(cmp util.Comparator) { 
+	if cmp == nil {
+		cmp = util.BytewiseComparator{}
+	}
+	sort.Slice(self, func(i, j int) bool { 
+		return cmp.Compare(self[i].Key, self[j].Key) < 0 
+	})
+}
+
+// Search returns the index of the first entry with a key >= key under cmp,
+// or Len() if there is none. A nil cmp orders the keys bytewise.
+func (self IndexSlice) Search(key Slice, cmp util.Comparator) int { 
+	if cmp == nil {
+		cmp = util.BytewiseComparator{}
+	}
+	return sort.Search(len(self), func(i int) bool { 
+		return cmp.Compare(self[i].Key, key) >= 0 
+	})
 }
\ No newline at end of file
@@ -0,0 +1,178 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"encoding/binary"
+
+	"github.com/entuerto/taigaDB/util"
+)
+
+//---------------------------------------------------------------------------------------
+// Block Builder
+//---------------------------------------------------------------------------------------
+
+// BlockBuilder generates blocks where keys are prefix-compressed. The keys
+// must be added in increasing order according to the comparator.
+//
+// A BlockBuilder is used for the data blocks, the index block and the meta 
+// index block of a table.
+type BlockBuilder struct {
+	cmp util.Comparator
+
+	buffer   []byte
+	restarts []uint32
+
+	restartInterval int
+	// Number of entries emitted since restart
+	counter int
+	// Number of entries in the block
+	entries int
+
+	lastKey  Slice
+	finished bool
+
+	// Nil if the block has no hash index
+	hashIndex *blockHashIndexBuilder
+}
+
+// Creates a BlockBuilder that stores a full key every restartInterval
+// entries. If cmp is nil, keys are ordered as bytes.Compare.
+func NewBlockBuilder(restartInterval int, cmp util.Comparator) *BlockBuilder {
+	if restartInterval < 1 {
+		restartInterval = 1
+	}
+	if cmp == nil {
+		cmp = util.BytewiseComparator{}
+	}
+	return &BlockBuilder{
+		cmp: cmp,
+		restarts: []uint32{0},
+		restartInterval: restartInterval,
+	}
+}
+
+// EnableHashIndex makes the builder append a hash index of the keys to the
+// blocks, see DataBlockBinaryAndHash. utilRatio is the number of keys per 
+// bucket, 0.75 is used if it is not positive.
+func (self *BlockBuilder) EnableHashIndex(utilRatio float64) {
+	self.hashIndex = newBlockHashIndexBuilder(utilRatio)
+}
+
+// Add appends a key/value entry to the block. The key must be larger than
+// any previously added key.
+func (self *BlockBuilder) Add(key, value Slice) error {
+	if self.finished {
+		return ErrBlockBuilderFinished
+	}
+	if self.entries > 0 && self.cmp.Compare(key, self.lastKey) <= 0 {
+		return ErrBlockKeyOrder
+	}
+
+	shared := 0
+	if self.counter < self.restartInterval {
+		shared = util.SharedPrefix(self.lastKey, key)
+	} else {
+		// Restart compression
+		self.restarts = append(self.restarts, uint32(len(self.buffer)))
+		self.counter = 0
+	}
+
+	if self.hashIndex != nil {
+		self.hashIndex.add(key, len(self.restarts) - 1)
+	}
+
+	var buffer [3 * binary.MaxVarintLen32]byte
+
+	n := binary.PutUvarint(buffer[0:], uint64(shared))
+	n += binary.PutUvarint(buffer[n:], uint64(len(key) - shared))
+	n += binary.PutUvarint(buffer[n:], uint64(len(value)))
+
+	self.buffer = append(self.buffer, buffer[:n]...)
+	self.buffer = append(self.buffer, key[shared:]...)
+	self.buffer = append(self.buffer, value...)
+
+	self.lastKey = append(self.lastKey[:0], key...)
+	self.counter++
+	self.entries++
+
+	return nil
+}
+
+// Flush drops the block built so far and prepares the builder for the 
+// next block. The Block returned before calling Flush must not be used 
+// afterwards, its memory is reused.
+func (self *BlockBuilder) Flush() error {
+	self.buffer   = self.buffer[:0]
+	self.restarts = self.restarts[:1]
+	self.counter  = 0
+	self.entries  = 0
+	self.lastKey  = self.lastKey[:0]
+	self.finished = false
+
+	if self.hashIndex != nil {
+		self.hashIndex.reset()
+	}
+	return nil
+}
+
+// Finish appends the restart array, and the hash index if enabled, to the 
+// block. No more entries can be added until Flush is called.
+func (self *BlockBuilder) Finish() error {
+	if self.finished {
+		return ErrBlockBuilderFinished
+	}
+
+	numRestarts := uint32(len(self.restarts))
+	withHash := self.hashIndex != nil && self.hashIndex.valid && self.EstimatedSize() <= hashIndexMaxBlockSize
+
+	var buffer [4]byte
+
+	for _, r := range self.restarts {
+		binary.LittleEndian.PutUint32(buffer[0:], r)
+		self.buffer = append(self.buffer, buffer[:]...)
+	}
+	if withHash {
+		self.buffer = self.hashIndex.finish(self.buffer)
+		numRestarts |= blockHashIndexFlag
+	}
+	binary.LittleEndian.PutUint32(buffer[0:], numRestarts)
+	self.buffer = append(self.buffer, buffer[:]...)
+
+	self.finished = true
+	return nil
+}
+
+// Block returns the block contents. Only valid after Finish.
+func (self *BlockBuilder) Block() Block {
+	if !self.finished {
+		return nil
+	}
+	return Block(self.buffer)
+}
+
+// Returns true if no entries have been added since the last Flush.
+func (self *BlockBuilder) Empty() bool {
+	return self.entries == 0
+}
+
+// Returns the number of entries added since the last Flush.
+func (self *BlockBuilder) Len() int {
+	return self.entries
+}
+
+// Returns an estimate of the size of the block we are building, including
+// the restart array and the hash index.
+func (self *BlockBuilder) EstimatedSize() int {
+	if self.finished {
+		return len(self.buffer)
+	}
+
+	size := len(self.buffer) + 4 * len(self.restarts) + 4
+	if self.hashIndex != nil && self.hashIndex.valid {
+		size += self.hashIndex.estimatedSize()
+	}
+	return size
+}
@@ -0,0 +1,116 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Rebuilds every data block of the LevelDB generated table and compares
+// the result byte by byte.
+func TestBlockBuilderLevelDBCompatible(t *testing.T) {
+	table, err := NewReader("../data/h.no-compression.sst", DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	sst := table.(*ssTable)
+	bb  := NewBlockBuilder(16, nil)
+
+	for _, idx := range sst.BlockIndex {
+		block, err := sst.readBlock(&idx.Handle)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		iter := NewEntryIterator(block)
+		for entry, ok := iter.Next(); ok; entry, ok = iter.Next() {
+			if err := bb.Add(entry.Key, entry.Value); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := bb.Finish(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(bb.Block(), block) {
+			t.Errorf("Block at %v is not byte identical", idx.Handle)
+		}
+		bb.Flush()
+	}
+}
+
+func TestBlockBuilderRestarts(t *testing.T) {
+	bb := NewBlockBuilder(2, nil)
+
+	for i := 0; i < 5; i++ {
+		if err := bb.Add(testKey(i), testValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if bb.Len() != 5 {
+		t.Errorf("Should be 5 entries, got %d", bb.Len())
+	}
+
+	estimate := bb.EstimatedSize()
+
+	if err := bb.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	block := bb.Block()
+	if len(block) != estimate {
+		t.Errorf("Estimated size %d, got %d", estimate, len(block))
+	}
+
+	restarts := block.Restarts()
+	if len(restarts) != 3 {
+		t.Fatalf("Should be 3 restarts, got %v", restarts)
+	}
+
+	for i, r := range restarts {
+		var entry BlockEntry
+		readBlockEntry(block[r:], &entry)
+
+		if entry.Shared != 0 || !bytes.Equal(entry.Key, testKey(2 * i)) {
+			t.Errorf("Restart %d should start with %s, got %v", i, testKey(2 * i), entry)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		if e, err := block.Search(testKey(i), bb.cmp); err != nil || e == nil || !bytes.Equal(e.Value, testValue(i)) {
+			t.Errorf("Looking for %s, got %v", testKey(i), e)
+		}
+	}
+}
+
+func TestBlockBuilderErrors(t *testing.T) {
+	bb := NewBlockBuilder(16, nil)
+
+	if err := bb.Add(Slice("b"), nil); err != nil {
+		t.Error(err)
+	}
+	if err := bb.Add(Slice("a"), nil); err != ErrBlockKeyOrder {
+		t.Errorf("Should be ErrBlockKeyOrder, got %v", err)
+	}
+	if bb.Block() != nil {
+		t.Error("Block should be nil before Finish")
+	}
+
+	bb.Finish()
+	if err := bb.Add(Slice("c"), nil); err != ErrBlockBuilderFinished {
+		t.Errorf("Should be ErrBlockBuilderFinished, got %v", err)
+	}
+
+	bb.Flush()
+	if !bb.Empty() {
+		t.Error("Builder should be empty after Flush")
+	}
+	if err := bb.Add(Slice("a"), nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+/*
+Data blocks can end with a hash index, in the same format as RocksDB. The index
+maps the hash of a key to the restart interval holding the key, a point lookup
+then only decodes the entries of a restart interval.
+
+    +---------------------------------+
+    | Restarts (uint32[num_restarts]) |
+    +---------------------------------+
+    | Buckets (uint8[num_buckets])    |  Index of a restart interval, or 254 if keys of
+    +---------------------------------+  many intervals collide, or 255 if no key.
+    | Number of buckets (uint16)      |
+    +---------------------------------+
+    | Number of restarts (uint32)     |  The most significant bit is set.
+    +---------------------------------+
+
+Blocks with more than 253 restart intervals or larger than 64K have no hash index.
+*/
+
+const (
+	// Flag set in the number of restarts of blocks with a hash index
+	blockHashIndexFlag = uint32(1) << 31
+
+	// Bucket values of keys in many restart intervals and of no key
+	hashIndexCollision = 254
+	hashIndexNoEntry   = 255
+
+	// Maximum number of restart intervals of a block with a hash index
+	hashIndexMaxRestarts = 253
+	// Maximum size of a block with a hash index
+	hashIndexMaxBlockSize = 1 << 16
+
+	// Seed of the key hash, the same as RocksDB
+	hashIndexSeed = 397
+
+	// Default number of keys per bucket
+	defaultHashIndexUtilRatio = 0.75
+)
+
+// Returns the hash of a key in the hash index.
+func blockKeyHash(key []byte) uint32 {
+	return hash32(key, hashIndexSeed)
+}
+
+// hash32 is the hash of RocksDB. It differs from the hash of LevelDB as the 
+// last bytes are sign extended.
+func hash32(b []byte, seed uint32) uint32 {
+	const m = 0xc6a4a793
+
+	h := seed ^ uint32(len(b)) * m
+	for ; len(b) >= 4; b = b[4:] {
+		h += binary.LittleEndian.Uint32(b)
+		h *= m
+		h ^= h >> 16
+	}
+	switch len(b) {
+	case 3:
+		h += uint32(int8(b[2])) << 16
+		fallthrough
+	case 2:
+		h += uint32(int8(b[1])) << 8
+		fallthrough
+	case 1:
+		h += uint32(int8(b[0]))
+		h *= m
+		h ^= h >> 24
+	}
+	return h
+}
+
+//---------------------------------------------------------------------------------------
+// Hash Index Builder
+//---------------------------------------------------------------------------------------
+
+type hashIndexEntry struct {
+	hash    uint32
+	restart uint8
+}
+
+type blockHashIndexBuilder struct {
+	bucketsPerKey float64
+
+	entries []hashIndexEntry
+	// False once a key is added past the last restart interval supported
+	valid bool
+}
+
+func newBlockHashIndexBuilder(utilRatio float64) *blockHashIndexBuilder {
+	if utilRatio <= 0 {
+		utilRatio = defaultHashIndexUtilRatio
+	}
+	return &blockHashIndexBuilder{
+		bucketsPerKey: 1 / utilRatio,
+		valid: true,
+	}
+}
+
+func (self *blockHashIndexBuilder) add(key Slice, restart int) {
+	if restart > hashIndexMaxRestarts {
+		self.valid = false
+		return
+	}
+	self.entries = append(self.entries, hashIndexEntry{blockKeyHash(key), uint8(restart)})
+}
+
+func (self *blockHashIndexBuilder) numBuckets() int {
+	n := float64(len(self.entries)) * self.bucketsPerKey
+	if n > math.MaxUint16 {
+		n = math.MaxUint16
+	}
+	return int(n) | 1
+}
+
+// Returns the size of the hash index.
+func (self *blockHashIndexBuilder) estimatedSize() int {
+	return self.numBuckets() + 2
+}
+
+// Appends the buckets and their number to buf.
+func (self *blockHashIndexBuilder) finish(buf []byte) []byte {
+	numBuckets := self.numBuckets()
+
+	start := len(buf)
+	for i := 0; i < numBuckets; i++ {
+		buf = append(buf, hashIndexNoEntry)
+	}
+	buckets := buf[start:]
+
+	for _, e := range self.entries {
+		i := e.hash % uint32(numBuckets)
+		switch buckets[i] {
+		case hashIndexNoEntry:
+			buckets[i] = e.restart
+		case e.restart:
+		default:
+			buckets[i] = hashIndexCollision
+		}
+	}
+
+	var buffer [2]byte
+	binary.LittleEndian.PutUint16(buffer[0:], uint16(numBuckets))
+	return append(buf, buffer[:]...)
+}
+
+func (self *blockHashIndexBuilder) reset() {
+	self.entries = self.entries[:0]
+	self.valid = true
+}
@@ -0,0 +1,200 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/entuerto/taigaDB/util"
+)
+
+func TestHash32(t *testing.T) {
+	// The want numbers come from the hash tests of RocksDB, the last
+	// bytes are sign extended.
+	testCases := []struct {
+		s    string
+		want uint32
+	}{
+		{"", 3164544308},
+		{"\x08", 422599524},
+		{"\x17", 3168152998},
+		{"\x9a", 3195034349},
+		{"\x1c", 2651681383},
+		{"\x4d\x76", 2447836956},
+		{"\x52\xd5", 3854228105},
+		{"\x91\xf7", 31066776},
+	}
+	for _, tc := range testCases {
+		if got := hash32([]byte(tc.s), 0xbc9f1d34); got != tc.want {
+			t.Errorf("s=%q: got %d, want %d", tc.s, got, tc.want)
+		}
+	}
+}
+
+func buildHashIndexBlock(t *testing.T, n, restartInterval int) Block {
+	bb := NewBlockBuilder(restartInterval, nil)
+	bb.EnableHashIndex(0.75)
+
+	for i := 0; i < n; i++ {
+		if err := bb.Add(testKey(i), testValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	estimate := bb.EstimatedSize()
+	if err := bb.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if block := bb.Block(); len(block) != estimate {
+		t.Errorf("Estimated size should be %d, got %d", len(block), estimate)
+	}
+	return bb.Block()
+}
+
+func TestBlockHashIndex(t *testing.T) {
+	const n = 100
+
+	block := buildHashIndexBlock(t, n, 4)
+	if block.NumberOfRestarts() != 25 {
+		t.Errorf("Should have 25 restarts, got %d", block.NumberOfRestarts())
+	}
+	if buckets := block.NumberOfHashBuckets(); buckets != 133 {
+		t.Errorf("Should have 133 hash buckets, got %d", buckets)
+	}
+
+	for i := 0; i < n; i++ {
+		e, err := block.Search(testKey(i), util.BytewiseComparator{})
+		if err != nil || e == nil || !bytes.Equal(e.Value, testValue(i)) {
+			t.Fatalf("Looking for %s, got %v %v", testKey(i), e, err)
+		}
+	}
+	for _, key := range []string{"a", "key000000a", "key000049a", "zzz"} {
+		if e, err := block.Search(Slice(key), util.BytewiseComparator{}); err != nil || e != nil {
+			t.Errorf("Looking for %s, should not be found, got %v %v", key, e, err)
+		}
+	}
+
+	// Iterators ignore the hash index
+	var i int
+	iter := NewEntryIterator(block)
+	for entry, ok := iter.Next(); ok; entry, ok = iter.Next() {
+		if !bytes.Equal(entry.Key, testKey(i)) {
+			t.Fatalf("Should be %s, got %s", testKey(i), entry.Key)
+		}
+		i++
+	}
+	if i != n || iter.Error() != nil {
+		t.Errorf("Should iterate over %d entries, got %d %v", n, i, iter.Error())
+	}
+}
+
+func TestBlockHashIndexTooManyRestarts(t *testing.T) {
+	const n = hashIndexMaxRestarts + 10
+
+	block := buildHashIndexBlock(t, n, 1)
+	if block.NumberOfHashBuckets() != 0 {
+		t.Errorf("Blocks with %d restarts should have no hash index", n)
+	}
+	if block.NumberOfRestarts() != n {
+		t.Errorf("Should have %d restarts, got %d", n, block.NumberOfRestarts())
+	}
+
+	for i := 0; i < n; i++ {
+		if e, err := block.Search(testKey(i), util.BytewiseComparator{}); err != nil || e == nil {
+			t.Fatalf("Looking for %s, got %v %v", testKey(i), e, err)
+		}
+	}
+}
+
+func TestBlockHashIndexCorruption(t *testing.T) {
+	block := buildHashIndexBlock(t, 100, 4)
+
+	// Point every bucket past the last restart interval
+	buckets := len(block) - 6 - block.NumberOfHashBuckets()
+	for i := buckets; i < len(block) - 6; i++ {
+		block[i] = 200
+	}
+
+	_, err := block.Search(testKey(10), util.BytewiseComparator{})
+
+	var corruption *CorruptionError
+	if !errors.As(err, &corruption) || !errors.Is(err, ErrBlockHashIndex) {
+		t.Fatalf("Should be a hash index corruption, got %v", err)
+	}
+	if corruption.Offset < buckets || corruption.Offset >= len(block) - 6 {
+		t.Errorf("Corruption should be in the buckets, got offset %d", corruption.Offset)
+	}
+
+	// A hash index too large for the block
+	block = buildHashIndexBlock(t, 100, 4)
+	block[len(block) - 6] = 0xff
+	block[len(block) - 5] = 0xff
+	if block.NumberOfRestarts() != 0 {
+		t.Errorf("Should have no restarts, got %d", block.NumberOfRestarts())
+	}
+	if _, err := block.Search(testKey(10), util.BytewiseComparator{}); !errors.Is(err, ErrBlockRestarts) {
+		t.Errorf("Should be ErrBlockRestarts, got %v", err)
+	}
+}
+
+func TestTableDataBlockHashIndex(t *testing.T) {
+	const n = 2000
+
+	opt := DefaultOptions()
+	opt.DataBlockIndexType = DataBlockBinaryAndHash
+
+	filename := writeTestTable(t, n, opt)
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	stats, err := ReadBlockStats(table, table.(*ssTable).BlockIndex[0].Handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.NumHashBuckets == 0 {
+		t.Errorf("Data blocks should have a hash index, got %v", stats)
+	}
+
+	for i := 0; i < n; i++ {
+		if value, err := table.Read(testKey(i)); err != nil || !bytes.Equal(value, testValue(i)) {
+			t.Fatalf("Looking for %s, got %s %v", testKey(i), value, err)
+		}
+	}
+	for i := 0; i < n; i += 10 {
+		key := append(testKey(i), '.')
+		if _, err := table.Read(key); err != ErrNotFound {
+			t.Errorf("Looking for %s, should be ErrNotFound got %v", key, err)
+		}
+	}
+
+	iter := table.Iterator()
+
+	var i int
+	for iter.Next() {
+		i++
+	}
+	if i != n {
+		t.Errorf("Should iterate over %d entries, got %d", n, i)
+	}
+
+	report, err := Verify(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("Table should verify, got %v", report.Problems)
+	}
+
+	opt.DataBlockIndexType = DataBlockIndexType(2)
+	if _, err := NewWriter(t.TempDir() + "/test.sst", opt); err != ErrTableDataBlockIndex {
+		t.Errorf("Should be ErrTableDataBlockIndex, got %v", err)
+	}
+}
@@ -181,3 +181,56 @@ func BenchmarkDecodeFooter(b *testing.B) {
 		}
 	}
 }
+
+func TestVersionedFooter(t *testing.T) {
+	footer := NewVersionedFooter(NewHandle(10, 20), NewHandle(5000, 8000), XXHash64Checksum)
+
+	buf := make([]byte, VersionedFooterEncodedLength)
+	if n, err := footer.Encode(buf); err != nil || n != VersionedFooterEncodedLength {
+		t.Fatalf("Should encode %d bytes, got %d %v", VersionedFooterEncodedLength, n, err)
+	}
+
+	// Decoded from the end of a larger buffer
+	var decoded Footer
+
+	data := append(bytes.Repeat([]byte{0xff}, 10), buf...)
+	if _, err := decoded.Decode(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if *decoded.MetaIndexHandle != *footer.MetaIndexHandle || *decoded.BlockIndexHandle != *footer.BlockIndexHandle {
+		t.Errorf("Should be %v, got %v", footer, decoded)
+	}
+	if decoded.ChecksumType != XXHash64Checksum || decoded.Version != FormatVersion {
+		t.Errorf("Should be checksum %d version %d, got %v", XXHash64Checksum, FormatVersion, decoded)
+	}
+	if decoded.MagicNumber != VersionedTableMagicNumber {
+		t.Errorf("Should be 0x88e241b785f4cff7, got: 0x%x", decoded.MagicNumber)
+	}
+
+	// A LevelDB footer at the end of a larger buffer
+	data = append(bytes.Repeat([]byte{0xff}, 10), testFooterData...)
+	if _, err := decoded.Decode(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Version != 0 || decoded.ChecksumType != CRC32CChecksum || decoded.BlockIndexHandle.Offset != 30 {
+		t.Errorf("Should be a LevelDB footer, got %v", decoded)
+	}
+}
+
+func TestIndexSliceSort(t *testing.T) {
+	idx := IndexSlice{{Key: Slice("b")}, {Key: Slice("c")}, {Key: Slice("a")}}
+
+	idx.Sort()
+	if string(idx[0].Key) != "a" || string(idx[1].Key) != "b" || string(idx[2].Key) != "c" {
+		t.Errorf("Should be sorted bytewise, got %v", idx)
+	}
+
+	idx.SortFunc(reverseComparator{})
+	if string(idx[0].Key) != "c" || string(idx[1].Key) != "b" || string(idx[2].Key) != "a" {
+		t.Errorf("Should be sorted in reverse, got %v", idx)
+	}
+	if i := idx.Search(Slice("b"), reverseComparator{}); i != 1 {
+		t.Errorf("Should find b at 1, got %d", i)
+	}
+}
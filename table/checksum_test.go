@@ -0,0 +1,223 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/entuerto/taigaDB/cache"
+	"github.com/entuerto/taigaDB/util"
+)
+
+// Changes the value of key 5 in the table file.
+func corruptTestTable(t *testing.T, filename string) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := bytes.Index(data, testValue(5))
+	if i < 0 {
+		t.Fatal("Value not found in table")
+	}
+	data[i] ^= 0x20
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	for _, checksum := range []ChecksumType{CRC32CChecksum, XXHash64Checksum} {
+		opt := DefaultOptions()
+		opt.Checksum = checksum
+
+		filename := writeTestTable(t, 100, opt)
+		corruptTestTable(t, filename)
+
+		table, err := NewReader(filename, DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Not verified by default
+		if value, err := table.Read(testKey(5)); err != nil || bytes.Equal(value, testValue(5)) {
+			t.Errorf("Checksum %d: corrupted value should be returned, got %s %v", checksum, value, err)
+		}
+
+		verify := &ReadOptions{VerifyChecksums: VerifyChecksumsOn}
+		if _, err := table.Get(testKey(5), verify); err != ErrBlockCRC32Corruption {
+			t.Errorf("Checksum %d: should be ErrBlockCRC32Corruption, got %v", checksum, err)
+		}
+
+		iter := table.NewIterator(verify)
+		for iter.Next() {
+		}
+		if iter.Error() != ErrBlockCRC32Corruption {
+			t.Errorf("Checksum %d: iterator should fail with ErrBlockCRC32Corruption, got %v", checksum, iter.Error())
+		}
+		table.Close()
+
+		opt.VerifyChecksums = true
+		table, err = NewReader(filename, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := table.Read(testKey(5)); err != ErrBlockCRC32Corruption {
+			t.Errorf("Checksum %d: should be ErrBlockCRC32Corruption, got %v", checksum, err)
+		}
+		// Read options without VerifyChecksums use the table option
+		if _, err := table.Get(testKey(5), &ReadOptions{PrefixSameAsStart: true}); err != ErrBlockCRC32Corruption {
+			t.Errorf("Checksum %d: should be ErrBlockCRC32Corruption, got %v", checksum, err)
+		}
+		if _, err := table.Get(testKey(5), &ReadOptions{VerifyChecksums: VerifyChecksumsOff}); err != nil {
+			t.Errorf("Checksum %d: read options should skip verification, got %v", checksum, err)
+		}
+		table.Close()
+	}
+}
+
+func TestVerifyChecksumsFilterPartitions(t *testing.T) {
+	opt := partitionedOptions()
+
+	filename := writeTestTable(t, 1000, opt)
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partition := table.(*ssTable).filter.(*partitionedFilterReader).top[0].Handle
+	table.Close()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[partition.Offset] ^= 0x20
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The corrupt partition is not cached when the read verifies the
+	// checksums
+	opt.BlockCache = cache.NewLRU(1 << 20)
+	table, err = NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	key := cache.Key{ID: table.(*ssTable).cacheID, Offset: partition.Offset}
+
+	table.Get(testKey(0), &ReadOptions{VerifyChecksums: VerifyChecksumsOn})
+	if _, ok := opt.BlockCache.Get(key); ok {
+		t.Error("Corrupt filter partition should not be read")
+	}
+
+	table.Get(testKey(0), nil)
+	if _, ok := opt.BlockCache.Get(key); !ok {
+		t.Error("Filter partition should be read without verification")
+	}
+}
+
+func TestXXHash64Footer(t *testing.T) {
+	opt := DefaultOptions()
+	opt.Checksum = XXHash64Checksum
+	opt.VerifyChecksums = true
+
+	filename := writeTestTable(t, 1000, opt)
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	footer := data[len(data) - VersionedFooterEncodedLength:]
+	if magic := binary.LittleEndian.Uint64(footer[len(footer) - 8:]); magic != VersionedTableMagicNumber {
+		t.Errorf("Should be the versioned magic number, got 0x%x", magic)
+	}
+	if ChecksumType(footer[0]) != XXHash64Checksum {
+		t.Errorf("Should be xxHash64 checksum type, got %d", footer[0])
+	}
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	for i := 0; i < 1000; i++ {
+		if value, err := table.Read(testKey(i)); err != nil || !bytes.Equal(value, testValue(i)) {
+			t.Fatalf("Looking for %s, got %s %v", testKey(i), value, err)
+		}
+	}
+}
+
+// An unset checksum is CRC32C, with the footer of LevelDB.
+func TestZeroChecksum(t *testing.T) {
+	opt := DefaultOptions()
+	opt.Checksum = 0
+
+	filename := writeTestTable(t, 100, opt)
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var footer Footer
+	if _, err := footer.Decode(data); err != nil {
+		t.Fatal(err)
+	}
+	if footer.MagicNumber != TableMagicNumber || footer.ChecksumType != CRC32CChecksum {
+		t.Fatalf("Should be a LevelDB footer, got %v", footer)
+	}
+
+	// The index block trailer, after the compression type
+	end := footer.BlockIndexHandle.Offset + footer.BlockIndexHandle.Size + 1
+	if checksum := binary.LittleEndian.Uint32(data[end:]); checksum != util.Checksum32(data[footer.BlockIndexHandle.Offset:end]) {
+		t.Errorf("Should be a CRC32C trailer, got 0x%x", checksum)
+	}
+}
+
+// NoChecksum is stored as 0 in the footer.
+func TestNoChecksumFooter(t *testing.T) {
+	opt := DefaultOptions()
+	opt.Checksum = NoChecksum
+
+	filename := writeTestTable(t, 100, opt)
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	footer := data[len(data) - VersionedFooterEncodedLength:]
+	if footer[0] != 0 {
+		t.Errorf("Should be checksum type 0, got %d", footer[0])
+	}
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	if value, err := table.Get(testKey(5), &ReadOptions{VerifyChecksums: VerifyChecksumsOn}); err != nil || !bytes.Equal(value, testValue(5)) {
+		t.Errorf("Looking for %s, got %s %v", testKey(5), value, err)
+	}
+}
+
+func TestUnknownChecksumType(t *testing.T) {
+	opt := DefaultOptions()
+	opt.Checksum = ChecksumType(2)
+
+	if _, err := NewWriter(t.TempDir() + "/test.sst", opt); err != ErrTableChecksumType {
+		t.Errorf("Should be ErrTableChecksumType, got %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"code.google.com/p/snappy-go/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+)
+
+// A Codec compresses and decompresses the contents of a block. A Codec
+// must be safe for concurrent use.
+type Codec interface {
+	// Encode returns the compressed form of src. The returned slice may be
+	// a sub-slice of dst if dst was large enough to hold the result.
+	Encode(dst, src []byte) ([]byte, error)
+
+	// Decode returns the decompressed form of src. The returned slice may be
+	// a sub-slice of dst if dst was large enough to hold the result.
+	Decode(dst, src []byte) ([]byte, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs = map[Compression]Codec{
+		SnappyCompression: snappyCodec{},
+		LZ4Compression:    lz4Codec{},
+		ZstdCompression:   zstdCodec{},
+	}
+)
+
+// RegisterCodec makes a codec available for the given compression type. 
+// The compression type is stored in the block trailer, so it must fit in
+// a byte. Registering a type twice replaces the previous codec.
+func RegisterCodec(compression Compression, codec Codec) {
+	if compression <= NoCompression || compression > 0xff {
+		panic("table: RegisterCodec compression type out of range")
+	}
+	if codec == nil {
+		panic("table: RegisterCodec codec is nil")
+	}
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[compression] = codec
+}
+
+// LookupCodec returns the codec registered for the compression type.
+func LookupCodec(compression Compression) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	codec, ok := codecs[compression]
+	return codec, ok
+}
+
+//---------------------------------------------------------------------------------------
+// Built-in Codecs
+//---------------------------------------------------------------------------------------
+
+// Largest decompressed size of a block. The size prefix of a compressed block 
+// is not trusted, a corrupt prefix would allocate up to 4G. Larger blocks are 
+// written uncompressed.
+const maxDecodedSize = 64 << 20
+
+type snappyCodec struct{}
+
+func (snappyCodec) Encode(dst, src []byte) ([]byte, error) {
+	return snappy.Encode(dst, src)
+}
+
+func (snappyCodec) Decode(dst, src []byte) ([]byte, error) {
+	if size, err := snappy.DecodedLen(src); err != nil || size > maxDecodedSize {
+		return nil, ErrBlockReadCorruption
+	}
+	return snappy.Decode(dst, src)
+}
+
+// LZ4 and Zstd blocks start with the varint32 decompressed size, as 
+// written by RocksDB with format_version 2.
+
+type lz4Codec struct{}
+
+func (lz4Codec) Encode(dst, src []byte) ([]byte, error) {
+	n := binary.MaxVarintLen32 + lz4.CompressBlockBound(len(src))
+	if cap(dst) < n {
+		dst = make([]byte, n)
+	}
+	dst = dst[:n]
+
+	m := binary.PutUvarint(dst, uint64(len(src)))
+	if len(src) == 0 {
+		return dst[:m], nil
+	}
+
+	size, err := lz4.CompressBlock(src, dst[m:], nil)
+	if err != nil {
+		return nil, err
+	}
+	return dst[:m + size], nil
+}
+
+func (lz4Codec) Decode(dst, src []byte) ([]byte, error) {
+	size, n := decodedSize(src)
+	if n <= 0 {
+		return nil, ErrBlockReadCorruption
+	}
+
+	if cap(dst) < size {
+		dst = make([]byte, size)
+	}
+	dst = dst[:size]
+	if size == 0 {
+		return dst, nil
+	}
+
+	m, err := lz4.UncompressBlock(src[n:], dst)
+	if err != nil {
+		return nil, err
+	}
+	if m != size {
+		return nil, ErrBlockReadCorruption
+	}
+	return dst, nil
+}
+
+type zstdCodec struct{}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// The encoder and decoder are safe for concurrent use of EncodeAll and 
+// DecodeAll, they are shared by all tables.
+func zstdInit() error {
+	zstdOnce.Do(func() {
+		if zstdEncoder, zstdErr = zstd.NewWriter(nil); zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdErr
+}
+
+func (zstdCodec) Encode(dst, src []byte) ([]byte, error) {
+	if err := zstdInit(); err != nil {
+		return nil, err
+	}
+
+	var header [binary.MaxVarintLen32]byte
+	
+	n := binary.PutUvarint(header[:], uint64(len(src)))
+	dst = append(dst[:0], header[:n]...)
+
+	return zstdEncoder.EncodeAll(src, dst), nil
+}
+
+func (zstdCodec) Decode(dst, src []byte) ([]byte, error) {
+	if err := zstdInit(); err != nil {
+		return nil, err
+	}
+
+	size, n := decodedSize(src)
+	if n <= 0 {
+		return nil, ErrBlockReadCorruption
+	}
+
+	if cap(dst) < size {
+		dst = make([]byte, 0, size)
+	}
+
+	b, err := zstdDecoder.DecodeAll(src[n:], dst[:0])
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != size {
+		return nil, ErrBlockReadCorruption
+	}
+	return b, nil
+}
+
+// Returns the decompressed size prefix and the number of bytes read, or -1
+// if the size is malformed or larger than maxDecodedSize.
+func decodedSize(src []byte) (int, int) {
+	size, n := binary.Uvarint(src)
+	if n <= 0 || size > maxDecodedSize {
+		return 0, -1
+	}
+	return int(size), n
+}
@@ -0,0 +1,223 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes n keys with the values returned by value.
+func writeValuesTable(t *testing.T, n int, opt *Options, value func(i int) Slice) string {
+	filename := filepath.Join(t.TempDir(), "test.sst")
+
+	table, err := NewWriter(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := table.Write(testKey(i), value(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := table.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// Returns the compression type stored in the trailer of every data block.
+func dataBlockCompressions(t *testing.T, filename string) []Compression {
+	table, err := NewReader(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []Compression
+	for _, idx := range table.(*ssTable).BlockIndex {
+		types = append(types, Compression(data[idx.Handle.Offset + idx.Handle.Size]))
+	}
+	return types
+}
+
+func checkValuesTable(t *testing.T, filename string, n int, value func(i int) Slice) {
+	table, err := NewReader(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	iter := table.Iterator()
+	for i := 0; i < n; i++ {
+		if !iter.Next() {
+			t.Fatalf("Iterator stopped at %d: %v", i, iter.Error())
+		}
+		if !bytes.Equal(iter.Key(), testKey(i)) || !bytes.Equal(iter.Value(), value(i)) {
+			t.Fatalf("Entry %d does not match, got %s", i, iter.Key())
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	const n = 1000
+
+	compressible := func(i int) Slice {
+		return bytes.Repeat(testValue(i), 10)
+	}
+
+	plain := writeValuesTable(t, n, DefaultOptions(), compressible)
+	fi, _ := os.Stat(plain)
+
+	for _, compression := range []Compression{SnappyCompression, LZ4Compression, ZstdCompression} {
+		opt := DefaultOptions()
+		opt.Compression = compression
+
+		compressed := writeValuesTable(t, n, opt, compressible)
+
+		for _, c := range dataBlockCompressions(t, compressed) {
+			if c != compression {
+				t.Fatalf("Data blocks should be compressed with %d, got %d", compression, c)
+			}
+		}
+
+		if cfi, _ := os.Stat(compressed); cfi.Size() >= fi.Size() {
+			t.Errorf("Compressed table %d (%d bytes) should be smaller than %d bytes", compression, cfi.Size(), fi.Size())
+		}
+
+		checkValuesTable(t, compressed, n, compressible)
+	}
+}
+
+func TestSnappyIncompressible(t *testing.T) {
+	const n = 200
+
+	values := make([]Slice, n)
+	rnd := rand.New(rand.NewSource(1))
+	for i := range values {
+		values[i] = make(Slice, 100)
+		rnd.Read(values[i])
+	}
+	random := func(i int) Slice {
+		return values[i]
+	}
+
+	opt := DefaultOptions()
+	opt.Compression = SnappyCompression
+
+	filename := writeValuesTable(t, n, opt, random)
+
+	for _, c := range dataBlockCompressions(t, filename) {
+		if c != NoCompression {
+			t.Fatalf("Incompressible data blocks should be stored uncompressed, got %d", c)
+		}
+	}
+
+	checkValuesTable(t, filename, n, random)
+}
+
+func TestCodecs(t *testing.T) {
+	inputs := []Slice{
+		nil,
+		Slice("a"),
+		bytes.Repeat(Slice("taigaDB "), 1000),
+	}
+
+	for _, compression := range []Compression{SnappyCompression, LZ4Compression, ZstdCompression} {
+		codec, ok := LookupCodec(compression)
+		if !ok {
+			t.Fatalf("Codec %d is not registered", compression)
+		}
+
+		for _, input := range inputs {
+			encoded, err := codec.Encode(nil, input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := codec.Decode(nil, encoded)
+			if err != nil {
+				t.Fatalf("Codec %d: %v", compression, err)
+			}
+			if !bytes.Equal(decoded, input) {
+				t.Errorf("Codec %d: round trip failed for %d bytes", compression, len(input))
+			}
+		}
+	}
+}
+
+func TestCodecsDecodedSize(t *testing.T) {
+	for _, compression := range []Compression{SnappyCompression, LZ4Compression, ZstdCompression} {
+		codec, _ := LookupCodec(compression)
+
+		encoded, err := codec.Encode(nil, bytes.Repeat(Slice("taigaDB "), 1000))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, n := binary.Uvarint(encoded)
+
+		// The decompressed size prefix of a corrupt block
+		for _, size := range []uint64{maxDecodedSize + 1, 0xffffffff} {
+			var prefix [binary.MaxVarintLen64]byte
+			corrupt := append(prefix[:binary.PutUvarint(prefix[:], size)], encoded[n:]...)
+
+			if _, err := codec.Decode(nil, corrupt); err != ErrBlockReadCorruption {
+				t.Errorf("Codec %d: size %d should be ErrBlockReadCorruption, got %v", compression, size, err)
+			}
+		}
+	}
+}
+
+// Application codec, with the built-in Zstd codec under another type.
+type testCodec struct {
+	zstdCodec
+}
+
+func TestRegisterCodec(t *testing.T) {
+	const testCompression = Compression(0x80)
+
+	opt := DefaultOptions()
+	opt.Compression = testCompression
+
+	if _, err := NewWriter(filepath.Join(t.TempDir(), "test.sst"), opt); err != ErrTableBlockCompression {
+		t.Errorf("Should be ErrTableBlockCompression, got %v", err)
+	}
+
+	RegisterCodec(testCompression, testCodec{})
+
+	compressible := func(i int) Slice {
+		return bytes.Repeat(testValue(i), 10)
+	}
+
+	filename := writeValuesTable(t, 100, opt, compressible)
+	for _, c := range dataBlockCompressions(t, filename) {
+		if c != testCompression {
+			t.Fatalf("Data blocks should use the registered codec, got %d", c)
+		}
+	}
+	checkValuesTable(t, filename, 100, compressible)
+
+	codecsMu.Lock()
+	delete(codecs, testCompression)
+	codecsMu.Unlock()
+
+	table, err := NewReader(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	if _, err := table.Read(testKey(0)); err != ErrTableBlockCompression {
+		t.Errorf("Should be ErrTableBlockCompression, got %v", err)
+	}
+}
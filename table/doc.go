@@ -22,9 +22,11 @@ Table Structure:
     +------------------+
     | Filter block     | 
     +------------------+    
-    | Meta index block | 
-    +------------------+    
     | Index block      |
+    +------------------+    
+    | Properties block | 
+    +------------------+    
+    | Meta index block | 
     +------------------+        +-------------------------+
     | Footer           |   ->   | Meta index block handle |  Table footer: 48 bytes long
     +------------------+        +-------------------------+
@@ -35,6 +37,19 @@ Table Structure:
                                 |  magic (8-bytes)        |  The magic are first 64-bit of SHA-1 sum 
                                 +-------------------------+  of "http://code.google.com/p/leveldb/"
 
+Tables using another checksum than CRC32C, or compressed with LZ4 or Zstd, are 
+written with the versioned footer of RocksDB: 53 bytes long, with a 1-byte checksum 
+type before the block handles and the 32-bit format version before the magic.
+
+The properties block records statistics of the table, see Properties, and the name 
+of its comparator; a reader opened with another comparator rejects the table. LevelDB 
+writes the index block after the meta index block and no properties block, both 
+layouts are read.
+
+With a prefix extractor, the prefixes of the keys are added to the filter with the keys,
+and the name of the extractor is recorded in the properties. Iterators with the 
+PrefixSameAsStart read option then skip the tables without keys of a prefix.
+
 Blocks
 
 Blocks have one or many key/value entries followed by a block trailer structure.
@@ -52,6 +67,13 @@ The index block contains one entry per data block, where the key is a string >=
 in that data block and before the first key in the successive data block.  The value is the
 BlockHandle for the data block.
 
+With the TwoLevelIndex index type the index is partitioned: the data index entries are
+cut into partitions of about MetadataBlockSize bytes, written before a top-level index
+block, whose keys are the last index keys of the partitions and whose values are their handles.
+The footer points to the top-level index. With PartitionFilters the filter is cut at the
+same keys, the meta index entry "partitionedfilter.<policy>" then points to a top-level
+filter index.
+
 Data Block Structure:
 
     +---------------+  -> Restart point
@@ -86,6 +108,10 @@ Block Entry Structure:
 
     Shared == 0 for restart point.
 
+With the DataBlockBinaryAndHash data block index type, a hash index mapping the keys to
+their restart interval follows the restart array of data blocks, and the most significant
+bit of the number of restarts is set. The format is the one of RocksDB.
+
 Block in SSTable file: 
 
 An extra block "footer" is saved for each data block that indicates if the block is saved 
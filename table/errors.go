@@ -6,11 +6,17 @@ package table
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
 	ErrBlockReadCorruption = errors.New("Table.Block: Block read corruption")
 	ErrBlockCRC32Corruption = errors.New("Table.Block: Block checksum mismatch")
+	ErrBlockBuilderFinished = errors.New("Table.Block: Block builder already finished")
+	ErrBlockKeyOrder        = errors.New("Table.Block: Keys must be added in increasing order")
+	ErrBlockRestarts        = errors.New("Table.Block: Bad restart array")
+	ErrBlockEntryCorruption = errors.New("Table.Block: Bad block entry")
+	ErrBlockHashIndex       = errors.New("Table.Block: Bad hash index")
 
 	ErrDecodeSmallBuffer = errors.New("Decode: Buffer to small")
 	ErrDecodeNot64bits   = errors.New("Decode: Value is not 64bits")
@@ -20,7 +26,32 @@ var (
 	
 	ErrTableMagicNumber = errors.New("Table: Wrong table format")
 	ErrTableBlockCompression = errors.New("Table.Block: Wrong compression format")
+	ErrTableChecksumType     = errors.New("Table: Unknown checksum type")
+	ErrTableIndexType        = errors.New("Table: Unknown index type")
+	ErrTableDataBlockIndex   = errors.New("Table: Unknown data block index type")
+	ErrTableComparator       = errors.New("Table: Comparator does not match the table comparator")
+	ErrTableIndex            = errors.New("Table: Index does not match the data blocks")
+	ErrTableProperties       = errors.New("Table: Properties do not match the table")
+
+	ErrTableKeyOrder     = errors.New("Table.Writer: Keys must be added in increasing order")
+	ErrTableWriterClosed = errors.New("Table.Writer: Table already closed")
+
+	ErrLayoutTable = errors.New("Table.Layout: Not a table of this package")
 
 	ErrNotFound = errors.New("Table: Value was not found")
 	ErrNotImplemented = errors.New("Table: Not implemented")
 )
+
+// CorruptionError reports malformed data at an offset of a block.
+type CorruptionError struct {
+	Offset int
+	Err    error
+}
+
+func (self *CorruptionError) Error() string {
+	return fmt.Sprintf("%v at offset %d", self.Err, self.Offset)
+}
+
+func (self *CorruptionError) Unwrap() error {
+	return self.Err
+}
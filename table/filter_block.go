@@ -0,0 +1,272 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/entuerto/taigaDB/util"
+)
+
+const (
+	// Generate a new filter every 2KB of data
+	filterBaseLg = 11
+	filterBase   = 1 << filterBaseLg
+
+	// Prefix of the meta index key of the filter block
+	filterMetaPrefix = "filter."
+
+	// Prefix of the meta index key of the top-level index of the filter 
+	// partitions
+	partitionedFilterMetaPrefix = "partitionedfilter."
+)
+
+/*
+Filter Block Structure:
+
+    +------------------------------+
+    | Filter 0                     |
+    +------------------------------+
+    | ...                          |
+    +------------------------------+
+    | Filter n                     |
+    +------------------------------+
+    | Filter offsets (uint32[n])   |  Offset of each filter in the block.
+    +------------------------------+
+    | Offset array start (uint32)  |
+    +------------------------------+
+    | Base lg (1-byte)             |  Filter i covers the data blocks with 
+    +------------------------------+  offset in [i * 2^lg, (i + 1) * 2^lg).
+*/
+
+//---------------------------------------------------------------------------------------
+// Filter Block Builder
+//---------------------------------------------------------------------------------------
+
+type filterBlockBuilder struct {
+	policy util.FilterPolicy
+	// Nil if the prefixes of the keys are not added
+	prefix util.PrefixExtractor
+	// Last prefix added to the current filter
+	lastPrefix Slice
+	hasPrefix  bool
+
+	// Flattened key contents and the start of each key
+	keys   []byte
+	starts []int
+
+	result  []byte
+	offsets []uint32
+}
+
+func newFilterBlockBuilder(policy util.FilterPolicy, prefix util.PrefixExtractor) *filterBlockBuilder {
+	return &filterBlockBuilder{
+		policy: policy,
+		prefix: prefix,
+	}
+}
+
+// Generates the filters of the data blocks before blockOffset.
+func (self *filterBlockBuilder) startBlock(blockOffset uint64) {
+	filterIndex := int(blockOffset / filterBase)
+	for filterIndex > len(self.offsets) {
+		self.generateFilter()
+	}
+}
+
+// Adds the key, and its prefix if it differs from the prefix of the 
+// previous key of the filter.
+func (self *filterBlockBuilder) addKey(key Slice) {
+	self.add(key)
+
+	if self.prefix == nil || !self.prefix.InDomain(key) {
+		return
+	}
+	prefix := self.prefix.Transform(key)
+	if self.hasPrefix && bytes.Equal(prefix, self.lastPrefix) {
+		return
+	}
+	self.add(prefix)
+	self.lastPrefix = append(self.lastPrefix[:0], prefix...)
+	self.hasPrefix  = true
+}
+
+func (self *filterBlockBuilder) add(key Slice) {
+	self.starts = append(self.starts, len(self.keys))
+	self.keys   = append(self.keys, key...)
+}
+
+// Returns the contents of the filter block.
+func (self *filterBlockBuilder) finish() Block {
+	if len(self.starts) > 0 {
+		self.generateFilter()
+	}
+
+	var buffer [4]byte
+
+	arrayOffset := uint32(len(self.result))
+	for _, offset := range self.offsets {
+		binary.LittleEndian.PutUint32(buffer[0:], offset)
+		self.result = append(self.result, buffer[:]...)
+	}
+	binary.LittleEndian.PutUint32(buffer[0:], arrayOffset)
+	self.result = append(self.result, buffer[:]...)
+	self.result = append(self.result, filterBaseLg)
+
+	return Block(self.result)
+}
+
+func (self *filterBlockBuilder) generateFilter() {
+	self.offsets = append(self.offsets, uint32(len(self.result)))
+	if len(self.starts) == 0 {
+		// Fast path if there are no keys for this filter
+		return
+	}
+
+	self.result = self.policy.NewFilter(self.result, self.takeKeys())
+}
+
+// Returns a single filter for the keys added since the last partition. 
+// Filter partitions are not mixed with startBlock and finish.
+func (self *filterBlockBuilder) finishPartition() Block {
+	return Block(self.policy.NewFilter(nil, self.takeKeys()))
+}
+
+// Returns the keys added since the last filter and resets them. The keys 
+// are only valid until the next addKey.
+func (self *filterBlockBuilder) takeKeys() [][]byte {
+	keys := make([][]byte, len(self.starts))
+	for i, start := range self.starts {
+		if i + 1 < len(self.starts) {
+			keys[i] = self.keys[start:self.starts[i + 1]]
+		} else {
+			keys[i] = self.keys[start:]
+		}
+	}
+
+	self.keys   = self.keys[:0]
+	self.starts = self.starts[:0]
+	self.hasPrefix = false
+
+	return keys
+}
+
+//---------------------------------------------------------------------------------------
+// Filter Block Reader
+//---------------------------------------------------------------------------------------
+
+// filterReader tells whether a data block may contain a key. The checksums
+// of the filter blocks read are verified if verify is true.
+type filterReader interface {
+	keyMayMatch(blockOffset uint64, key Slice, verify bool) bool
+
+	// Tells whether the data block at blockOffset, whose index key is 
+	// indexKey, may contain keys with the prefix.
+	prefixMayMatch(blockOffset uint64, indexKey, prefix Slice, verify bool) bool
+}
+
+type filterBlockReader struct {
+	policy util.FilterPolicy
+
+	data    Block
+	// Filter offsets followed by the offset array start
+	offsets Block
+	num     int
+	baseLg  uint
+}
+
+// Returns nil if the filter block contents are malformed.
+func newFilterBlockReader(policy util.FilterPolicy, contents Block) *filterBlockReader {
+	n := len(contents)
+	if n < 5 {
+		return nil
+	}
+
+	arrayOffset := int(binary.LittleEndian.Uint32(contents[n - 5:]))
+	if arrayOffset > n - 5 {
+		return nil
+	}
+
+	return &filterBlockReader{
+		policy: policy,
+		data: contents,
+		offsets: contents[arrayOffset:n - 1],
+		num: (n - 5 - arrayOffset) / 4,
+		baseLg: uint(contents[n - 1]),
+	}
+}
+
+// The filter block is read with the table, verify is ignored.
+func (self *filterBlockReader) keyMayMatch(blockOffset uint64, key Slice, verify bool) bool {
+	index := int(blockOffset >> self.baseLg)
+	if index >= self.num {
+		// Errors are treated as potential matches
+		return true
+	}
+
+	// The last filter ends where the offset array starts
+	start := binary.LittleEndian.Uint32(self.offsets[4 * index:])
+	limit := binary.LittleEndian.Uint32(self.offsets[4 * index + 4:])
+
+	switch {
+	case start == limit:
+		// Empty filters do not match any keys
+		return false
+	case start > limit || int(limit) > len(self.data):
+		return true
+	}
+
+	return self.policy.QueryFilter(self.data[start:limit]).KeyMayMatch(key)
+}
+
+func (self *filterBlockReader) prefixMayMatch(blockOffset uint64, indexKey, prefix Slice, verify bool) bool {
+	return self.keyMayMatch(blockOffset, prefix, verify)
+}
+
+//---------------------------------------------------------------------------------------
+// Partitioned Filter Reader
+//---------------------------------------------------------------------------------------
+
+// Reads the filter partitions through the block cache. The top-level index
+// selects the partition of a key, the partition has one filter for all its
+// keys.
+type partitionedFilterReader struct {
+	sst    *ssTable
+	policy util.FilterPolicy
+	top    IndexSlice
+}
+
+func newPartitionedFilterReader(sst *ssTable, policy util.FilterPolicy, top IndexSlice) *partitionedFilterReader {
+	return &partitionedFilterReader{
+		sst: sst,
+		policy: policy,
+		top: top,
+	}
+}
+
+func (self *partitionedFilterReader) keyMayMatch(blockOffset uint64, key Slice, verify bool) bool {
+	return self.mayMatch(key, key, verify)
+}
+
+// The partition of the data block is the one of its index key.
+func (self *partitionedFilterReader) prefixMayMatch(blockOffset uint64, indexKey, prefix Slice, verify bool) bool {
+	return self.mayMatch(indexKey, prefix, verify)
+}
+
+// Queries the filter of the partition of key for a key or a prefix.
+func (self *partitionedFilterReader) mayMatch(key, query Slice, verify bool) bool {
+	i := self.top.Search(key, self.sst.options.Comparator)
+	if i == self.top.Len() {
+		return true
+	}
+
+	data, err := self.sst.readDataBlock(&self.top[i].Handle, verify)
+	if err != nil {
+		// Errors are treated as potential matches
+		return true
+	}
+	return self.policy.QueryFilter(data).KeyMayMatch(query)
+}
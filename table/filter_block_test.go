@@ -0,0 +1,141 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/entuerto/taigaDB/util"
+)
+
+func TestEmptyFilterBlock(t *testing.T) {
+	builder := newFilterBlockBuilder(util.BloomFilterPolicy(10), nil)
+
+	block := builder.finish()
+	if !bytes.Equal(block, []byte{0, 0, 0, 0, filterBaseLg}) {
+		t.Fatalf("Empty filter block, got %v", block)
+	}
+
+	reader := newFilterBlockReader(util.BloomFilterPolicy(10), block)
+	if !reader.keyMayMatch(0, Slice("foo"), false) {
+		t.Error("Empty filter block should match all keys")
+	}
+	if !reader.keyMayMatch(100000, Slice("foo"), false) {
+		t.Error("Empty filter block should match all keys")
+	}
+}
+
+func TestMultiChunkFilterBlock(t *testing.T) {
+	builder := newFilterBlockBuilder(util.BloomFilterPolicy(10), nil)
+
+	// First filter
+	builder.startBlock(0)
+	builder.addKey(Slice("foo"))
+	builder.startBlock(2000)
+	builder.addKey(Slice("bar"))
+
+	// Second filter
+	builder.startBlock(3100)
+	builder.addKey(Slice("box"))
+
+	// Third filter is empty
+
+	// Last filter
+	builder.startBlock(9000)
+	builder.addKey(Slice("box"))
+	builder.addKey(Slice("hello"))
+
+	reader := newFilterBlockReader(util.BloomFilterPolicy(10), builder.finish())
+
+	tests := []struct {
+		offset uint64
+		key    string
+		want   bool
+	}{
+		{0, "foo", true},
+		{2000, "bar", true},
+		{0, "box", false},
+		{0, "hello", false},
+
+		{3100, "box", true},
+		{3100, "foo", false},
+		{3100, "bar", false},
+		{3100, "hello", false},
+
+		{4100, "foo", false},
+		{4100, "bar", false},
+		{4100, "box", false},
+		{4100, "hello", false},
+
+		{9000, "box", true},
+		{9000, "hello", true},
+		{9000, "foo", false},
+		{9000, "bar", false},
+	}
+
+	for _, tt := range tests {
+		if got := reader.keyMayMatch(tt.offset, Slice(tt.key), false); got != tt.want {
+			t.Errorf("keyMayMatch(%d, %s): got %v, want %v", tt.offset, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestTableFilter(t *testing.T) {
+	const n = 2000
+
+	opt := DefaultOptions()
+	opt.FilterPolicy = util.BloomFilterPolicy(10)
+
+	filename := writeTestTable(t, n, opt)
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	sst := table.(*ssTable)
+	if sst.filter == nil {
+		t.Fatal("Filter block was not loaded")
+	}
+	if len(sst.MetaIndex) != 2 || string(sst.MetaIndex[0].Key) != "filter.leveldb.BuiltinBloomFilter2" {
+		t.Errorf("Meta index should contain the filter and the properties, got %v", sst.MetaIndex)
+	}
+
+	for i := 0; i < n; i++ {
+		if value, err := table.Read(testKey(i)); err != nil || !bytes.Equal(value, testValue(i)) {
+			t.Fatalf("Looking for %s, got %s %v", testKey(i), value, err)
+		}
+	}
+
+	// Absent keys inside the table key range should rarely reach a data block.
+	var matches int
+	for i := 0; i < n - 1; i++ {
+		key := append(testKey(i), '.')
+
+		idx := sst.BlockIndex[sst.BlockIndex.Search(key, nil)]
+		if sst.filter.keyMayMatch(idx.Handle.Offset, key, false) {
+			matches++
+		}
+		if _, err := table.Read(key); err != ErrNotFound {
+			t.Errorf("Looking for %s, should be ErrNotFound got %v", key, err)
+		}
+	}
+	if matches > n / 50 {
+		t.Errorf("Too many false positives: %d in %d", matches, n)
+	}
+
+	// A reader without a policy ignores the filter block.
+	plain, err := NewReader(filename, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+
+	if plain.(*ssTable).filter != nil {
+		t.Error("Filter should not be loaded without a filter policy")
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/entuerto/taigaDB/util"
+)
+
+// Returns a block of n entries with the given restart interval, and a hash 
+// index if hash is set.
+func fuzzBlock(n, restartInterval int, hash bool) []byte {
+	bb := NewBlockBuilder(restartInterval, nil)
+	if hash {
+		bb.EnableHashIndex(0.75)
+	}
+	for i := 0; i < n; i++ {
+		bb.Add(testKey(i), testValue(i))
+	}
+	bb.Finish()
+	return bb.Block()
+}
+
+// Decoding a malformed block must fail with a CorruptionError, without 
+// panics.
+func FuzzBlock(f *testing.F) {
+	f.Add(fuzzBlock(0, 16, false))
+	f.Add(fuzzBlock(1, 16, false))
+	f.Add(fuzzBlock(40, 4, false))
+	f.Add(fuzzBlock(40, 4, true))
+	f.Add([]byte{0, 0, 0})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{0, 0x80, 2, 'a', 'v', 0, 0, 0, 0, 1, 0, 0, 0})
+
+	cmp := util.BytewiseComparator{}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		b := Block(data)
+
+		checkErr := func(err error) {
+			var cerr *CorruptionError
+			if err != nil && !errors.As(err, &cerr) {
+				t.Fatalf("Should be a CorruptionError, got %v", err)
+			}
+		}
+
+		it := newBlockIterator(b, cmp)
+		for ok := it.SeekToFirst(); ok; ok = it.Next() {
+		}
+		checkErr(it.Error())
+
+		it = newBlockIterator(b, cmp)
+		for ok := it.SeekToLast(); ok; ok = it.Prev() {
+		}
+		checkErr(it.Error())
+
+		_, err := b.Search(Slice("key000010"), cmp)
+		checkErr(err)
+
+		iter := NewEntryIterator(b)
+		for _, ok := iter.Next(); ok; _, ok = iter.Next() {
+		}
+		checkErr(iter.Error())
+
+		if _, err := decodeIndexEntries(b); err != nil {
+			var cerr *CorruptionError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("Should be a CorruptionError, got %v", err)
+			}
+		}
+
+		var props Properties
+		checkErr(props.decode(b))
+	})
+}
+
+func FuzzBlockHandleDecode(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0x80})
+	f.Add([]byte{0x80, 0x01, 0x90, 0x20})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var bh BlockHandle
+
+		n, err := bh.Decode(data)
+		if err != nil {
+			return
+		}
+		if n <= 0 || n > len(data) {
+			t.Fatalf("Decoded %d bytes of %d", n, len(data))
+		}
+
+		var buffer [MaxEncodedLength]byte
+
+		m, err := bh.Encode(buffer[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var bh2 BlockHandle
+		if _, err := bh2.Decode(buffer[:m]); err != nil || bh2 != bh {
+			t.Fatalf("Round trip of %v: got %v %v", bh, bh2, err)
+		}
+	})
+}
+
+func FuzzFooterDecode(f *testing.F) {
+	var buffer [VersionedFooterEncodedLength]byte
+
+	n, _ := NewFooter(NewHandle(12975, 8), NewHandle(12988, 64)).Encode(buffer[:])
+	f.Add(append([]byte(nil), buffer[:n]...))
+
+	n, _ = NewVersionedFooter(NewHandle(1 << 40, 8), NewHandle(12988, 1 << 20), XXHash64Checksum).Encode(buffer[:])
+	f.Add(append([]byte(nil), buffer[:n]...))
+
+	f.Add(make([]byte, FooterEncodedLength - 1))
+	f.Add(bytes.Repeat([]byte{0xff}, VersionedFooterEncodedLength))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var footer Footer
+
+		n, err := footer.Decode(data)
+		if err != nil {
+			return
+		}
+		if n != footer.EncodedLength() || n > len(data) {
+			t.Fatalf("Decoded %d bytes of %d", n, len(data))
+		}
+
+		var buffer [VersionedFooterEncodedLength]byte
+
+		m, err := footer.Encode(buffer[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var footer2 Footer
+		if _, err := footer2.Decode(buffer[:m]); err != nil {
+			t.Fatal(err)
+		}
+		if *footer2.MetaIndexHandle != *footer.MetaIndexHandle || *footer2.BlockIndexHandle != *footer.BlockIndexHandle || 
+		   footer2.ChecksumType != footer.ChecksumType || footer2.Version != footer.Version {
+			t.Fatalf("Round trip of %v: got %v", footer, footer2)
+		}
+	})
+}
+
+// Returns a table of n entries written in memory.
+func fuzzTable(n int, opt *Options) []byte {
+	var buf bytes.Buffer
+
+	w, _ := NewStreamWriter(&buf, opt)
+	for i := 0; i < n; i++ {
+		w.Write(testKey(i), testValue(i))
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+// Returns data with the last footer handles replaced.
+func fuzzFooter(data []byte, metaIndexHandle, blockIndexHandle *BlockHandle) []byte {
+	data = append([]byte(nil), data...)
+	NewFooter(metaIndexHandle, blockIndexHandle).Encode(data[len(data) - FooterEncodedLength:])
+	return data
+}
+
+// Opening and reading a malformed table must fail without panics.
+func FuzzReaderAt(f *testing.F) {
+	data := fuzzTable(40, DefaultOptions())
+	f.Add(data)
+	f.Add(fuzzFooter(data, NewHandle(0, 1 << 62), NewHandle(0, 8)))
+	f.Add(fuzzFooter(data, NewHandle(16, math.MaxUint64 - 2), NewHandle(0, 8)))
+	f.Add(fuzzFooter(data, NewHandle(math.MaxUint64 - 2, 8), NewHandle(0, 8)))
+
+	opt := DefaultOptions()
+	opt.Compression = SnappyCompression
+	f.Add(fuzzTable(40, opt))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		opt := DefaultOptions()
+		// Corrupt blocks reach the decoders
+		opt.VerifyChecksums = false
+
+		table, err := NewReaderAt(bytes.NewReader(data), int64(len(data)), opt)
+		if err != nil {
+			return
+		}
+		defer table.Close()
+
+		iter := table.Iterator()
+		for iter.Next() {
+		}
+		for ok := iter.Seek(testKey(10)); ok; ok = iter.Prev() {
+		}
+
+		table.Read(testKey(20))
+	})
+}
@@ -5,17 +5,45 @@
 package table
 
 import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/entuerto/taigaDB/util"
 )
 
 // Iterator iterates over a Table's key/value pairs in key order.
+//
+// A new iterator is positioned before the first key/value pair, calling 
+// Next moves it to the first pair. Once exhausted, moving in the opposite
+// direction moves it back to the first or last pair.
 type Iterator interface {
 	// Is positioned at a valid node
 	Valid() bool
 
 	// Next moves the iterator to the next key/value pair.
-	// It returns whether the iterator is exhausted.
+	// It returns whether the iterator is positioned at a valid pair, false
+	// once it is exhausted.
 	Next() bool
 
+	// Prev moves the iterator to the previous key/value pair.
+	// It returns whether the iterator is positioned at a valid pair, false
+	// once it is exhausted.
+	Prev() bool
+
+	// Seek moves the iterator to the first key/value pair whose key is 
+	// greater than or equal to the given key.
+	// It returns whether the iterator is positioned at a valid pair.
+	Seek(key Slice) bool
+
+	// SeekToFirst moves the iterator to the first key/value pair.
+	// It returns whether the iterator is positioned at a valid pair.
+	SeekToFirst() bool
+
+	// SeekToLast moves the iterator to the last key/value pair.
+	// It returns whether the iterator is positioned at a valid pair.
+	SeekToLast() bool
+
 	// Key returns the key of the current key/value pair, or nil if done.
 	// The caller should not modify the returned contents.
 	Key() Slice
@@ -23,70 +51,655 @@ type Iterator interface {
 	// Value returns the value of the current key/value pair, or nil if done.
 	// The caller should not modify the returned contents.
 	Value() Slice
+
+	// Error returns the error that made the iterator exhausted, if any.
+	Error() error
 }
 
 //---------------------------------------------------------------------------------------
-// SSTable Iterator
+// Two-Level Iterator
 //---------------------------------------------------------------------------------------
 
-type ssTableIterator struct {
-	sst *ssTable
+// Two-level iterator, the entries of the index iterator select the block 
+// iterated over. It iterates over the data blocks of a table, and over the
+// index partitions of a two-level index.
+type twoLevelIterator struct {
+	// Values are encoded block handles
+	index Iterator
+	// Returns the iterator of the block of an index entry value
+	newBlock func(value Slice) (Iterator, error)
+
+	// Nil if the index iterator is not positioned on an entry
+	data Iterator
+
+	err error
+}
+
+func newTwoLevelIterator(index Iterator, newBlock func(value Slice) (Iterator, error)) *twoLevelIterator {
+	return &twoLevelIterator{
+		index: index,
+		newBlock: newBlock,
+	}
+}
+
+func (self twoLevelIterator) Valid() bool {
+	return self.data != nil && self.data.Valid()
+}
+
+func (self *twoLevelIterator) Next() bool {
+	if self.data != nil && self.data.Next() {
+		return true
+	}
+	return self.skipEmptyBlocksForward()
+}
+
+func (self *twoLevelIterator) Prev() bool {
+	if self.data != nil && self.data.Prev() {
+		return true
+	}
+	return self.skipEmptyBlocksBackward()
+}
+
+func (self *twoLevelIterator) Seek(key Slice) bool {
+	if !self.loadBlock(self.index.Seek(key)) {
+		return false
+	}
+
+	if self.data.Seek(key) {
+		return true
+	}
+	return self.skipEmptyBlocksForward()
+}
+
+func (self *twoLevelIterator) SeekToFirst() bool {
+	if !self.loadBlock(self.index.SeekToFirst()) {
+		return false
+	}
+
+	if self.data.SeekToFirst() {
+		return true
+	}
+	return self.skipEmptyBlocksForward()
+}
+
+func (self *twoLevelIterator) SeekToLast() bool {
+	if !self.loadBlock(self.index.SeekToLast()) {
+		return false
+	}
+
+	if self.data.SeekToLast() {
+		return true
+	}
+	return self.skipEmptyBlocksBackward()
+}
+
+func (self twoLevelIterator) Key() Slice {
+	if self.Valid() {
+		return self.data.Key()
+	}
+	return nil
+}
+
+func (self twoLevelIterator) Value() Slice {
+	if self.Valid() {
+		return self.data.Value()
+	}
+	return nil
+}
+
+func (self twoLevelIterator) Error() error {
+	return self.err
+}
+
+// Loads the block of the current index entry, if positioned is true. The 
+// block iterator is left unpositioned.
+func (self *twoLevelIterator) loadBlock(positioned bool) bool {
+	self.data = nil
+
+	if self.err == nil {
+		self.err = self.index.Error()
+	}
+	if self.err != nil || !positioned {
+		return false
+	}
+
+	data, err := self.newBlock(self.index.Value())
+	if err != nil {
+		self.err = err
+		return false
+	}
+
+	self.data = data
+	return true
+}
+
+func (self *twoLevelIterator) skipEmptyBlocksForward() bool {
+	for self.data == nil || !self.data.Valid() {
+		if self.data != nil && self.data.Error() != nil {
+			self.err = self.data.Error()
+		}
+		if self.err != nil || !self.loadBlock(self.index.Next()) {
+			self.data = nil
+			return false
+		}
+		self.data.SeekToFirst()
+	}
+	return true
+}
+
+func (self *twoLevelIterator) skipEmptyBlocksBackward() bool {
+	for self.data == nil || !self.data.Valid() {
+		if self.data != nil && self.data.Error() != nil {
+			self.err = self.data.Error()
+		}
+		if self.err != nil || !self.loadBlock(self.index.Prev()) {
+			self.data = nil
+			return false
+		}
+		self.data.SeekToLast()
+	}
+	return true
+}
+
+//---------------------------------------------------------------------------------------
+// Index Iterator
+//---------------------------------------------------------------------------------------
+
+// Iterator over the entries of a decoded index block, the values are the 
+// encoded block handles.
+type indexIterator struct {
+	cmp util.Comparator
 	idx IndexSlice
 
+	// Position in the index, -1 before the first entry and idx.Len() after 
+	// the last entry.
 	pos int
+
+	buffer [MaxEncodedLength]byte
 }
 
-func (self ssTableIterator) Valid() bool {
-	return self.pos != self.idx.Len()
+func newIndexIterator(idx IndexSlice, cmp util.Comparator) *indexIterator {
+	return &indexIterator{
+		cmp: cmp,
+		idx: idx,
+		pos: -1,
+	}
 }
 
-func (self *ssTableIterator) Next() bool {
-	if self.Valid() {
+func (self indexIterator) Valid() bool {
+	return self.pos >= 0 && self.pos < self.idx.Len()
+}
+
+func (self *indexIterator) Next() bool {
+	if self.pos < self.idx.Len() {
 		self.pos++
 	}
-	
 	return self.Valid()
 }
 
-func (self ssTableIterator) Key() Slice {
+func (self *indexIterator) Prev() bool {
+	switch {
+	case self.pos < 0:
+		return false
+	case self.pos >= self.idx.Len():
+		return self.SeekToLast()
+	}
+
+	self.pos--
+	return self.Valid()
+}
+
+func (self *indexIterator) Seek(key Slice) bool {
+	self.pos = self.idx.Search(key, self.cmp)
+	return self.Valid()
+}
+
+func (self *indexIterator) SeekToFirst() bool {
+	self.pos = 0
+	return self.Valid()
+}
+
+func (self *indexIterator) SeekToLast() bool {
+	self.pos = self.idx.Len() - 1
+	return self.Valid()
+}
+
+func (self indexIterator) Key() Slice {
 	if self.Valid() {
 		return self.idx[self.pos].Key
 	}
 	return nil
 }
 
-func (self ssTableIterator) Value() Slice {
-	if self.Valid() {
+func (self *indexIterator) Value() Slice {
+	if !self.Valid() {
 		return nil
 	}
+
+	n, _ := self.idx[self.pos].Handle.Encode(self.buffer[:])
+	return Slice(self.buffer[:n])
+}
+
+func (self indexIterator) Error() error {
+	return nil
+}
+
+//---------------------------------------------------------------------------------------
+// Prefix Iterator
+//---------------------------------------------------------------------------------------
+
+// Iterates over the keys with the prefix of the key of the last Seek. The 
+// iteration stops at the first key out of the prefix, in both directions.
+type prefixIterator struct {
+	iter      Iterator
+	extractor util.PrefixExtractor
+	// Returns false if the table has no key >= key with the prefix of key
+	mayMatch func(key Slice) (bool, error)
+
+	// Prefix of the last Seek, if prefixed is set
+	prefix   Slice
+	prefixed bool
+
+	// Whether the table has no key with the prefix
+	skipped bool
+	// 1 or -1 once the iteration left the prefix forward or backward
+	exhausted int
+
+	err error
+}
+
+func newPrefixIterator(iter Iterator, extractor util.PrefixExtractor, mayMatch func(key Slice) (bool, error)) *prefixIterator {
+	return &prefixIterator{
+		iter: iter,
+		extractor: extractor,
+		mayMatch: mayMatch,
+	}
+}
+
+func (self prefixIterator) Valid() bool {
+	return !self.skipped && self.exhausted == 0 && self.iter.Valid()
+}
+
+func (self *prefixIterator) Next() bool {
+	if self.skipped || self.exhausted > 0 {
+		return false
+	}
+	self.exhausted = 0
+	return self.check(self.iter.Next(), 1)
+}
+
+func (self *prefixIterator) Prev() bool {
+	if self.skipped || self.exhausted < 0 {
+		return false
+	}
+	self.exhausted = 0
+	return self.check(self.iter.Prev(), -1)
+}
+
+func (self *prefixIterator) Seek(key Slice) bool {
+	self.reset()
+
+	if !self.extractor.InDomain(key) {
+		return self.check(self.iter.Seek(key), 1)
+	}
+	self.prefix   = append(self.prefix[:0], self.extractor.Transform(key)...)
+	self.prefixed = true
+
+	ok, err := self.mayMatch(key)
+	if err != nil || !ok {
+		self.err = err
+		self.skipped = true
+		return false
+	}
+	return self.check(self.iter.Seek(key), 1)
+}
+
+func (self *prefixIterator) SeekToFirst() bool {
+	self.reset()
+	return self.check(self.iter.SeekToFirst(), 1)
+}
+
+func (self *prefixIterator) SeekToLast() bool {
+	self.reset()
+	return self.check(self.iter.SeekToLast(), -1)
+}
+
+func (self prefixIterator) Key() Slice {
+	if self.Valid() {
+		return self.iter.Key()
+	}
 	return nil
 }
 
+func (self prefixIterator) Value() Slice {
+	if self.Valid() {
+		return self.iter.Value()
+	}
+	return nil
+}
+
+func (self prefixIterator) Error() error {
+	if self.err != nil {
+		return self.err
+	}
+	return self.iter.Error()
+}
+
+func (self *prefixIterator) reset() {
+	self.prefixed  = false
+	self.skipped   = false
+	self.exhausted = 0
+	self.err       = nil
+}
+
+// Marks the iteration exhausted in the direction unless the iterator is 
+// positioned at a key with the prefix.
+func (self *prefixIterator) check(ok bool, direction int) bool {
+	if ok && self.prefixed {
+		key := self.iter.Key()
+		ok = self.extractor.InDomain(key) && bytes.Equal(self.extractor.Transform(key), self.prefix)
+	}
+	if !ok {
+		self.exhausted = direction
+	}
+	return ok
+}
+
+//---------------------------------------------------------------------------------------
+// Block Iterator
+//---------------------------------------------------------------------------------------
+
+type blockIterator struct {
+	cmp util.Comparator
+
+	data Block
+	// Offset of the restart array
+	restarts    int
+	numRestarts int
+	// Buckets of the hash index, nil if the block has none
+	buckets Slice
+
+	// Offset of the current entry, -1 before the first entry and restarts
+	// after the last entry
+	current int
+	// Offset of the entry following the current entry
+	next int
+	// Index of the restart interval containing the current entry
+	restartIndex int
+
+	entry BlockEntry
+	err   error
+}
+
+func newBlockIterator(b Block, cmp util.Comparator) *blockIterator {
+	iter := &blockIterator{
+		cmp: cmp,
+		data: b,
+		current: -1,
+	}
+
+	if err := b.checkRestarts(); err != nil {
+		iter.err = err
+		return iter
+	}
+
+	iter.numRestarts, iter.buckets = b.footer()
+	iter.restarts = b.RestartStartOffset()
+	return iter
+}
+
+func (self blockIterator) Valid() bool {
+	return self.current >= 0 && self.current < self.restarts
+}
+
+func (self *blockIterator) Next() bool {
+	switch {
+	case self.current < 0:
+		return self.SeekToFirst()
+	case self.current >= self.restarts:
+		return false
+	}
+	return self.parseNextEntry()
+}
+
+func (self *blockIterator) Prev() bool {
+	switch {
+	case self.current < 0:
+		return false
+	case self.current >= self.restarts:
+		return self.SeekToLast()
+	}
+
+	// Scan backwards to a restart point before the current entry
+	original := self.current
+	for self.restartPoint(self.restartIndex) >= original {
+		if self.restartIndex == 0 {
+			// No more entries
+			self.current = -1
+			return false
+		}
+		self.restartIndex--
+	}
+
+	// Loop until end of current entry hits the start of original entry
+	self.seekToRestartPoint(self.restartIndex)
+	for self.parseNextEntry() && self.next < original {
+	}
+	return self.Valid()
+}
+
+func (self *blockIterator) Seek(key Slice) bool {
+	if self.numRestarts == 0 {
+		self.current = self.restarts
+		return false
+	}
+
+	// Binary search in the restart array to find the first restart point
+	// with a key >= target
+	var entry BlockEntry
+	pos := sort.Search(self.numRestarts, func(i int) bool {
+		if self.err != nil {
+			return true
+		}
+
+		offset := self.restartPoint(i)
+		if offset > self.restarts {
+			self.corruption(self.restarts + 4 * i, ErrBlockRestarts)
+			return true
+		}
+
+		entry.Key = entry.Key[:0]
+		if _, err := readBlockEntry(self.data[offset:self.restarts], &entry); err != nil {
+			self.corruption(offset, err)
+			return true
+		}
+
+		return self.cmp.Compare(entry.Key, key) >= 0
+	})
+	if self.err != nil {
+		return false
+	}
+
+	// Keys before the restart point may still be >= target
+	if pos > 0 {
+		pos--
+	}
+
+	// Linear search (within restart interval) for first key >= target
+	self.seekToRestartPoint(pos)
+	for self.parseNextEntry() {
+		if self.cmp.Compare(self.entry.Key, key) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Positions the iterator at the entry with key, returns false if the block
+// does not contain key. The hash index is used if the block has one.
+func (self *blockIterator) seekExact(key Slice) bool {
+	if self.buckets == nil {
+		return self.Seek(key) && self.cmp.Compare(self.entry.Key, key) == 0
+	}
+
+	b := int(blockKeyHash(key) % uint32(len(self.buckets)))
+	switch i := int(self.buckets[b]); {
+	case i == hashIndexNoEntry:
+		self.current = self.restarts
+		return false
+	case i == hashIndexCollision:
+		return self.Seek(key) && self.cmp.Compare(self.entry.Key, key) == 0
+	case i >= self.numRestarts:
+		self.corruption(len(self.data) - 6 - len(self.buckets) + b, ErrBlockHashIndex)
+		return false
+	default:
+		// Linear search within the restart interval of the key
+		self.seekToRestartPoint(i)
+		for self.parseNextEntry() && self.restartIndex == i {
+			if c := self.cmp.Compare(self.entry.Key, key); c >= 0 {
+				return c == 0
+			}
+		}
+		return false
+	}
+}
+
+func (self *blockIterator) SeekToFirst() bool {
+	if self.numRestarts == 0 {
+		self.current = self.restarts
+		return false
+	}
+
+	self.seekToRestartPoint(0)
+	return self.parseNextEntry()
+}
+
+func (self *blockIterator) SeekToLast() bool {
+	if self.numRestarts == 0 {
+		self.current = self.restarts
+		return false
+	}
+
+	self.seekToRestartPoint(self.numRestarts - 1)
+	for self.parseNextEntry() && self.next < self.restarts {
+	}
+	return self.Valid()
+}
+
+func (self blockIterator) Key() Slice {
+	if self.Valid() {
+		return self.entry.Key
+	}
+	return nil
+}
+
+func (self blockIterator) Value() Slice {
+	if self.Valid() {
+		return self.entry.Value
+	}
+	return nil
+}
+
+func (self blockIterator) Error() error {
+	return self.err
+}
+
+func (self blockIterator) restartPoint(i int) int {
+	return int(binary.LittleEndian.Uint32(self.data[self.restarts + 4 * i:]))
+}
+
+// Stops the iteration on a malformed entry or restart point at offset.
+func (self *blockIterator) corruption(offset int, err error) {
+	self.err = &CorruptionError{Offset: offset, Err: err}
+	self.current = self.restarts
+	self.next = self.restarts
+}
+
+func (self *blockIterator) seekToRestartPoint(i int) {
+	self.entry.Key   = self.entry.Key[:0]
+	self.restartIndex = i
+	self.next = self.restartPoint(i)
+
+	if self.next > self.restarts {
+		self.corruption(self.restarts + 4 * i, ErrBlockRestarts)
+	}
+}
+
+// Decodes the entry following the current entry. 
+func (self *blockIterator) parseNextEntry() bool {
+	self.current = self.next
+	if self.current >= self.restarts {
+		// No more entries to return
+		self.current = self.restarts
+		return false
+	}
+
+	rest, err := readBlockEntry(self.data[self.current:self.restarts], &self.entry)
+	if err != nil {
+		self.corruption(self.current, err)
+		return false
+	}
+	self.next = self.restarts - len(rest)
+
+	for self.restartIndex + 1 < self.numRestarts && self.restartPoint(self.restartIndex + 1) <= self.current {
+		self.restartIndex++
+	}
+	return true
+}
+
 //---------------------------------------------------------------------------------------
 // Block Entry Iterator
 //---------------------------------------------------------------------------------------
 
 type EntryIterator interface {
 	Next() (*BlockEntry, bool)
+
+	// Offset of the current entry in the block
+	Offset() int
+
+	// Error returns the error that stopped the iteration, if any.
+	Error() error
 }
 
 type blockEntryIterator struct {
 	data Block
+	// Offset of the next entry and of the current entry
+	next   int
+	offset int
 
 	entry BlockEntry
+	err   error
 }
 
 func (self *blockEntryIterator) Next() (*BlockEntry, bool) {
-	if len(self.data) == 0 {
+	if self.err != nil || self.next == len(self.data) {
 		return nil, false
 	}
+	self.offset = self.next
 
-	self.data = readBlockEntry(self.data, &self.entry)
+	rest, err := readBlockEntry(self.data[self.next:], &self.entry)
+	if err != nil {
+		self.err = &CorruptionError{Offset: self.next, Err: err}
+		return nil, false
+	}
+	self.next = len(self.data) - len(rest)
 	
 	return &self.entry, true
 }
 
+func (self *blockEntryIterator) Offset() int {
+	return self.offset
+}
+
+func (self *blockEntryIterator) Error() error {
+	return self.err
+}
+
 func NewEntryIterator(b Block) EntryIterator {
+	if err := b.checkRestarts(); err != nil {
+		return &blockEntryIterator{err: err}
+	}
+
 	return &blockEntryIterator{
 		data: b[:b.RestartStartOffset()],
 	}
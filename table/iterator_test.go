@@ -0,0 +1,230 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"testing"
+)
+
+func openTestTable(t testing.TB, n int, opt *Options) TableReader {
+	filename := writeTestTable(t, n, opt)
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+func checkIterator(t *testing.T, iter Iterator, i int) {
+	if !iter.Valid() {
+		t.Fatalf("Iterator should be valid at %s", testKey(i))
+	}
+	if !bytes.Equal(iter.Key(), testKey(i)) || !bytes.Equal(iter.Value(), testValue(i)) {
+		t.Fatalf("Should be %s: %s, got %s: %s", testKey(i), testValue(i), iter.Key(), iter.Value())
+	}
+}
+
+func TestIteratorForwardBackward(t *testing.T) {
+	const n = 2000
+
+	table := openTestTable(t, n, DefaultOptions())
+	defer table.Close()
+
+	iter := table.Iterator()
+	if iter.Valid() {
+		t.Error("New iterator should not be valid")
+	}
+
+	var i int
+	for iter.Next() {
+		checkIterator(t, iter, i)
+		i++
+	}
+	if i != n {
+		t.Errorf("Should iterate over %d entries, got %d", n, i)
+	}
+	if iter.Key() != nil || iter.Value() != nil {
+		t.Error("Exhausted iterator should return nil key and value")
+	}
+
+	for iter.Prev() {
+		i--
+		checkIterator(t, iter, i)
+	}
+	if i != 0 {
+		t.Errorf("Should iterate back to 0, stopped at %d", i)
+	}
+	if iter.Error() != nil {
+		t.Error(iter.Error())
+	}
+}
+
+func TestIteratorSeek(t *testing.T) {
+	const n = 2000
+
+	opt := DefaultOptions()
+	opt.BlockRestartInterval = 7
+
+	table := openTestTable(t, n, opt)
+	defer table.Close()
+
+	iter := table.Iterator()
+
+	for i := 0; i < n; i += 37 {
+		if !iter.Seek(testKey(i)) {
+			t.Fatalf("Seek(%s) failed", testKey(i))
+		}
+		checkIterator(t, iter, i)
+
+		// Between keys
+		if !iter.Seek(append(testKey(i), 0)) && i + 1 < n {
+			t.Fatalf("Seek(%s.) failed", testKey(i))
+		}
+		if i + 1 < n {
+			checkIterator(t, iter, i + 1)
+		}
+	}
+
+	if !iter.Seek(Slice("a")) {
+		t.Fatal("Seek before first key should be valid")
+	}
+	checkIterator(t, iter, 0)
+
+	if iter.Seek(Slice("z")) {
+		t.Fatal("Seek after last key should not be valid")
+	}
+	if !iter.Prev() {
+		t.Fatal("Prev after the last key should be valid")
+	}
+	checkIterator(t, iter, n - 1)
+}
+
+func TestIteratorSeekToFirstLast(t *testing.T) {
+	const n = 500
+
+	table := openTestTable(t, n, DefaultOptions())
+	defer table.Close()
+
+	iter := table.Iterator()
+
+	if !iter.SeekToLast() {
+		t.Fatal("SeekToLast failed")
+	}
+	checkIterator(t, iter, n - 1)
+	if iter.Next() {
+		t.Error("Next after the last key should not be valid")
+	}
+
+	if !iter.SeekToFirst() {
+		t.Fatal("SeekToFirst failed")
+	}
+	checkIterator(t, iter, 0)
+	if iter.Prev() {
+		t.Error("Prev before the first key should not be valid")
+	}
+}
+
+// Mixes directions across data block boundaries.
+func TestIteratorPrevAcrossBlocks(t *testing.T) {
+	const n = 2000
+
+	table := openTestTable(t, n, DefaultOptions())
+	defer table.Close()
+
+	sst := table.(*ssTable)
+	iter := table.Iterator()
+
+	for _, idx := range sst.BlockIndex[:len(sst.BlockIndex) - 1] {
+		if !iter.Seek(idx.Key) || !iter.Next() {
+			t.Fatalf("Could not move past %s", idx.Key)
+		}
+		next := string(iter.Key())
+
+		if !iter.Prev() || !bytes.Equal(iter.Key(), idx.Key) {
+			t.Fatalf("Prev from %s should be %s, got %s", next, idx.Key, iter.Key())
+		}
+		if !iter.Next() || string(iter.Key()) != next {
+			t.Fatalf("Next from %s should be %s, got %s", idx.Key, next, iter.Key())
+		}
+	}
+}
+
+func TestIteratorEmptyTable(t *testing.T) {
+	table := openTestTable(t, 0, DefaultOptions())
+	defer table.Close()
+
+	iter := table.Iterator()
+	if iter.Next() || iter.Prev() || iter.SeekToFirst() || iter.SeekToLast() || iter.Seek(Slice("a")) {
+		t.Error("Iterator over an empty table should never be valid")
+	}
+}
+
+func TestIteratorLevelDBTable(t *testing.T) {
+	table, err := NewReader("../data/h.no-compression.sst", DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	var keys []string
+
+	iter := table.Iterator()
+	for iter.Next() {
+		if len(keys) > 0 && keys[len(keys) - 1] >= string(iter.Key()) {
+			t.Fatalf("Keys out of order: %s >= %s", keys[len(keys) - 1], iter.Key())
+		}
+		keys = append(keys, string(iter.Key()))
+	}
+
+	if !iter.Seek(Slice("school")) || string(iter.Value()) != "1" {
+		t.Errorf("Looking for school, got %s: %s", iter.Key(), iter.Value())
+	}
+
+	count := len(keys)
+	for ok := iter.SeekToLast(); ok; ok = iter.Prev() {
+		count--
+		if keys[count] != string(iter.Key()) {
+			t.Fatalf("Should be %s, got %s", keys[count], iter.Key())
+		}
+	}
+	if count != 0 {
+		t.Errorf("Backward iteration missed %d keys", count)
+	}
+}
+
+func TestBlockIterator(t *testing.T) {
+	for _, interval := range []int{1, 2, 3, 16} {
+		bb := NewBlockBuilder(interval, nil)
+		for i := 0; i < 50; i++ {
+			bb.Add(testKey(i), testValue(i))
+		}
+		bb.Finish()
+
+		iter := newBlockIterator(bb.Block(), bb.cmp)
+		if iter.Prev() {
+			t.Errorf("Interval %d: Prev on a new iterator should not be valid", interval)
+		}
+
+		iter.SeekToLast()
+		for i := 49; i >= 0; i-- {
+			checkIterator(t, iter, i)
+			if iter.Prev() != (i > 0) {
+				t.Fatalf("Interval %d: Prev failed at %d", interval, i)
+			}
+		}
+		if !iter.Next() || !bytes.Equal(iter.Key(), testKey(0)) {
+			t.Errorf("Interval %d: Next before the first key should move to the first key", interval)
+		}
+
+		for i := 0; i < 50; i++ {
+			if !iter.Seek(testKey(i)) {
+				t.Fatalf("Interval %d: Seek failed at %d", interval, i)
+			}
+			checkIterator(t, iter, i)
+		}
+	}
+}
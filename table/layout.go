@@ -0,0 +1,140 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"fmt"
+)
+
+// Layout describes where the blocks of a table are stored. It is meant for 
+// tools inspecting tables, like cmd/sstdump.
+type Layout struct {
+	// Size of the table in bytes
+	Size int64
+
+	Footer     Footer
+	MetaIndex  IndexSlice
+	// Index entries of the data blocks
+	BlockIndex IndexSlice
+	// Top-level index of a two-level index, nil if the index is not 
+	// partitioned
+	IndexPartitions IndexSlice
+
+	// Nil if the table has no properties
+	Properties *Properties
+}
+
+// BlockStats holds the statistics of a data block.
+type BlockStats struct {
+	Handle BlockHandle
+
+	// Compression type from the block trailer
+	Compression Compression
+
+	// Size of the block contents after decompression
+	RawSize int
+
+	NumEntries  int
+	NumRestarts int
+	// Number of buckets of the hash index, 0 if the block has none
+	NumHashBuckets int
+
+	// First and last key of the block, nil if the block is empty
+	FirstKey Slice
+	LastKey  Slice
+}
+
+func (self BlockStats) String() string {
+	return fmt.Sprintf("BlockStats { Handle: %v, Compression: %v, RawSize: %d, Entries: %d, Restarts: %d, HashBuckets: %d}", 
+		              self.Handle, 
+		              self.Compression,
+		              self.RawSize,
+		              self.NumEntries,
+		              self.NumRestarts,
+		              self.NumHashBuckets)
+}
+
+// ReadLayout returns the layout of a table opened with NewReader, 
+// NewReaderAt or NewReader with the UseMmap option. The index partitions 
+// of a two-level index are read.
+func ReadLayout(t TableReader) (*Layout, error) {
+	sst, ok := t.(*ssTable)
+	if !ok {
+		return nil, ErrLayoutTable
+	}
+
+	layout := &Layout{
+		Size:       sst.size,
+		Footer:     sst.footer,
+		MetaIndex:  sst.MetaIndex,
+		BlockIndex: sst.BlockIndex,
+		Properties: sst.properties,
+	}
+
+	if sst.partitioned {
+		layout.IndexPartitions = sst.BlockIndex
+		layout.BlockIndex = nil
+
+		iter := sst.newBlockIndexIterator(sst.options.VerifyChecksums)
+		for iter.SeekToFirst(); iter.Valid(); iter.Next() {
+			ie := &IndexEntry{Key: append(Slice(nil), iter.Key()...)}
+			if _, err := ie.Handle.Decode(iter.Value()); err != nil {
+				return nil, err
+			}
+			layout.BlockIndex = append(layout.BlockIndex, ie)
+		}
+		if err := iter.Error(); err != nil {
+			return nil, err
+		}
+	}
+
+	return layout, nil
+}
+
+// ReadBlockStats reads and decodes the data block of the handle to collect
+// its statistics.
+func ReadBlockStats(t TableReader, handle BlockHandle) (*BlockStats, error) {
+	sst, ok := t.(*ssTable)
+	if !ok {
+		return nil, ErrLayoutTable
+	}
+	bh := &handle
+
+	if end := bh.Offset + bh.Size + BlockTrailerSize; end < bh.Offset || end > uint64(sst.size) {
+		return nil, ErrBlockReadCorruption
+	}
+
+	var trailer [1]byte
+	if err := sst.readAt(trailer[:], int64(bh.Offset + bh.Size)); err != nil {
+		return nil, err
+	}
+
+	block, err := sst.readBlock(bh)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &BlockStats{
+		Handle:      *bh,
+		Compression: Compression(trailer[0]),
+		RawSize:     len(block),
+		NumRestarts: block.NumberOfRestarts(),
+		NumHashBuckets: block.NumberOfHashBuckets(),
+	}
+
+	iter := NewEntryIterator(block)
+	for entry, ok := iter.Next(); ok; entry, ok = iter.Next() {
+		if stats.NumEntries == 0 {
+			stats.FirstKey = Slice(append([]byte(nil), entry.Key...))
+		}
+		stats.LastKey = append(stats.LastKey[:0], entry.Key...)
+		stats.NumEntries++
+	}
+
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"testing"
+)
+
+func TestReadLayout(t *testing.T) {
+	table, err := NewReader("../data/h.no-compression.sst", DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	layout, err := ReadLayout(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if layout.Footer.MagicNumber != TableMagicNumber || layout.Footer.Version != 0 {
+		t.Errorf("Should have a LevelDB footer, got %v", layout.Footer)
+	}
+	if layout.Size != 13105 || len(layout.BlockIndex) != 4 || len(layout.MetaIndex) != 0 {
+		t.Errorf("Unexpected layout: %d bytes, %d data blocks, %d meta blocks", 
+			layout.Size, len(layout.BlockIndex), len(layout.MetaIndex))
+	}
+	if layout.Properties != nil {
+		t.Errorf("LevelDB tables have no properties, got %v", layout.Properties)
+	}
+
+	var entries int
+	for i := range layout.BlockIndex {
+		stats, err := ReadBlockStats(table, layout.BlockIndex[i].Handle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Handle != layout.BlockIndex[i].Handle || stats.Compression != NoCompression {
+			t.Errorf("Block %d: got %v", i, stats)
+		}
+		if string(stats.LastKey) > string(layout.BlockIndex[i].Key) {
+			t.Errorf("Block %d: last key %q after the index key %q", i, stats.LastKey, layout.BlockIndex[i].Key)
+		}
+		entries += stats.NumEntries
+	}
+	if entries != 1710 {
+		t.Errorf("Should have 1710 entries, got %d", entries)
+	}
+
+	if _, err := ReadBlockStats(table, BlockHandle{Offset: uint64(layout.Size), Size: 10}); err == nil {
+		t.Errorf("Reading a block past the index should fail")
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package table
+
+import (
+	"os"
+)
+
+// Memory mapped files are not supported, the tables are read from the file.
+func mmap(file *os.File, size int64) ([]byte, error) {
+	return nil, ErrNotImplemented
+}
+
+func munmap(b []byte) error {
+	return ErrNotImplemented
+}
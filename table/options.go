@@ -5,15 +5,107 @@
 package table
 
 import (
+	"fmt"
+
+	"github.com/entuerto/taigaDB/cache"
 	"github.com/entuerto/taigaDB/util"
 )
 
-// Compression algorithm for block entries.
+// Compression algorithm for block entries. The value is stored in the block
+// trailer, the same values as RocksDB are used.
 type Compression int
 
 const (
-	NoCompression      Compression = iota
-	SnappyCompression
+	NoCompression     Compression = 0
+	SnappyCompression Compression = 1
+	LZ4Compression    Compression = 4
+	ZstdCompression   Compression = 7
+)
+
+// Returns the RocksDB name of the compression.
+func (self Compression) String() string {
+	switch self {
+	case NoCompression:
+		return "NoCompression"
+	case SnappyCompression:
+		return "Snappy"
+	case LZ4Compression:
+		return "LZ4"
+	case ZstdCompression:
+		return "ZSTD"
+	}
+	return fmt.Sprintf("Compression(%d)", int(self))
+}
+
+// Checksum algorithm of the block trailers. The same values as RocksDB are
+// stored in the footer, but NoChecksum, stored as 0, is not the zero value:
+// an unset Options.Checksum is CRC32C.
+type ChecksumType int
+
+const (
+	NoChecksum       ChecksumType = -1
+	CRC32CChecksum   ChecksumType = 1
+	XXHash64Checksum ChecksumType = 3
+)
+
+// Returns the RocksDB name of the checksum.
+func (self ChecksumType) String() string {
+	switch self {
+	case NoChecksum:
+		return "NoChecksum"
+	case CRC32CChecksum:
+		return "CRC32c"
+	case XXHash64Checksum:
+		return "xxHash64"
+	}
+	return fmt.Sprintf("ChecksumType(%d)", int(self))
+}
+
+// Returns the value stored in the footer.
+func (self ChecksumType) id() byte {
+	if self == NoChecksum {
+		return 0
+	}
+	return byte(self)
+}
+
+// Returns the checksum of a value stored in the footer.
+func checksumTypeOf(id byte) ChecksumType {
+	if id == 0 {
+		return NoChecksum
+	}
+	return ChecksumType(id)
+}
+
+// Index layout of a table. The same values as RocksDB are used.
+type IndexType int
+
+const (
+	// One index block with an entry per data block
+	BinarySearchIndex IndexType = 0
+	// Index partitions with a top-level index over the partitions
+	TwoLevelIndex IndexType = 2
+)
+
+// Index of the keys of a data block. The same values as RocksDB are used.
+type DataBlockIndexType int
+
+const (
+	// Binary search in the restart array
+	DataBlockBinarySearch DataBlockIndexType = 0
+	// Hash index of the keys to their restart interval, binary search is 
+	// used by iterators and when keys collide in the hash index
+	DataBlockBinaryAndHash DataBlockIndexType = 1
+)
+
+// Checksum verification of a single read.
+type ChecksumVerification int
+
+const (
+	// Verify the checksums if Options.VerifyChecksums is set
+	VerifyChecksumsDefault ChecksumVerification = 0
+	VerifyChecksumsOn      ChecksumVerification = 1
+	VerifyChecksumsOff     ChecksumVerification = 2
 )
 
 // Options holds the parameters for the table implementation.
@@ -38,23 +130,123 @@ type Options struct {
 	// The default value uses the same ordering as bytes.Compare.
 	Comparator util.Comparator
 
-	// Compress blocks using the specified compression algorithm.
+	// Compress blocks using the specified compression algorithm. A codec
+	// must be registered for the algorithm, see RegisterCodec. A block
+	// is stored uncompressed when compression saves less than 12.5%.
 	//
 	// The default value is no compression.
 	Compression Compression
 
-	// Whether to verify the per-block checksums in a table.
+	// If non-nil, use the specified filter policy to reduce disk reads.
+	// The filter block is written and read only when a policy is set.
+	//
+	// The default value is nil.
+	FilterPolicy util.FilterPolicy
+
+	// If non-nil, the prefixes of the keys are added to the filter with the
+	// keys. Iterators with the PrefixSameAsStart read option use the filter
+	// to skip the tables without keys of the prefix.
+	//
+	// The default value is nil.
+	PrefixExtractor util.PrefixExtractor
+
+	// Layout of the index. With TwoLevelIndex, the index is split in 
+	// partitions of about MetadataBlockSize bytes and a top-level index 
+	// selects the partition of a key. The reader only keeps the top-level
+	// index in memory, the partitions are read on demand through the 
+	// block cache.
+	//
+	// The default value is BinarySearchIndex.
+	IndexType IndexType
+
+	// Whether to partition the filter with the index. Each filter partition
+	// holds a single filter for the keys of the data blocks of an index 
+	// partition. Only used with TwoLevelIndex and a filter policy.
+	//
+	// The default value is false.
+	PartitionFilters bool
+
+	// Approximate size of the index and filter partitions.
+	//
+	// The default value is 4096 (4K).
+	MetadataBlockSize int
+
+	// Index of the keys of the data blocks. With DataBlockBinaryAndHash,
+	// each data block ends with a hash index used by point lookups. Keys
+	// equal under the comparator must have the same bytes.
+	//
+	// The default value is DataBlockBinarySearch.
+	DataBlockIndexType DataBlockIndexType
+
+	// Number of keys per bucket of the data block hash index. Lower values
+	// use more space and have fewer collisions.
+	//
+	// The default value is 0.75.
+	DataBlockHashTableUtilRatio float64
+
+	// Checksum algorithm of the block trailers. A table with another checksum 
+	// than CRC32C is written with a versioned footer, and cannot be read by 
+	// LevelDB. The zero value is CRC32C.
+	//
+	// The default value is CRC32C.
+	Checksum ChecksumType
+
+	// If non-nil, the data blocks are cached in the block cache. A cache can
+	// be shared by many tables.
+	//
+	// The default value is nil.
+	BlockCache *cache.LRU
+
+	// Number of the table file, its blocks are cached under the file number.
+	// Readers of the same file share the cached blocks, which stay cached 
+	// when a reader is closed. With 0, each reader caches its own blocks, 
+	// erased on Close. File numbers must be below 1 << 63.
+	//
+	// The default value is 0.
+	FileNumber uint64
+
+	// Whether NewReader memory maps the table file. Uncompressed blocks are
+	// then used without copies, the keys and values returned by the table 
+	// must not be used after Close. Ignored on platforms without mmap.
+	//
+	// The default value is false.
+	UseMmap bool
+
+	// Whether to verify the per-block checksums in a table. It can be 
+	// overridden for each read by ReadOptions.
 	//
 	// The default value is false.
 	VerifyChecksums bool
 }
 
+// ReadOptions holds the parameters of a single read.
+type ReadOptions struct {
+	// Whether to verify the per-block checksums of the blocks read. 
+	// VerifyChecksumsOn and VerifyChecksumsOff override 
+	// Options.VerifyChecksums.
+	//
+	// The default value is VerifyChecksumsDefault.
+	VerifyChecksums ChecksumVerification
+
+	// Whether iterators only return the keys with the prefix of the key 
+	// given to Seek, as returned by Options.PrefixExtractor. A table whose
+	// filter has no key of the prefix is skipped without reading its data
+	// blocks. Seek with a key outside the extractor domain, SeekToFirst and 
+	// SeekToLast iterate over all the keys.
+	//
+	// Ignored without Options.PrefixExtractor.
+	PrefixSameAsStart bool
+}
+
 func DefaultOptions() *Options {
 	return &Options{
 		BlockRestartInterval: 16,
 		BlockSize: 4096,
+		MetadataBlockSize: 4096,
+		DataBlockHashTableUtilRatio: 0.75,
 		Comparator: util.BytewiseComparator{},
 		Compression: NoCompression,
+		Checksum: CRC32CChecksum,
 		VerifyChecksums: false,
 	}
 }
\ No newline at end of file
@@ -0,0 +1,185 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/entuerto/taigaDB/util"
+)
+
+func partitionedOptions() *Options {
+	opt := DefaultOptions()
+	opt.IndexType = TwoLevelIndex
+	opt.MetadataBlockSize = 256
+	opt.FilterPolicy = util.BloomFilterPolicy(10)
+	opt.PartitionFilters = true
+	return opt
+}
+
+func TestPartitionedIndex(t *testing.T) {
+	const n = 5000
+
+	opt := partitionedOptions()
+
+	table := openTestTable(t, n, opt)
+	defer table.Close()
+
+	sst := table.(*ssTable)
+	if !sst.partitioned {
+		t.Fatal("Table should have a partitioned index")
+	}
+
+	props := table.Properties()
+	if props == nil || props.IndexPartitions < 2 {
+		t.Fatalf("Should have more than one index partition, got %v", props)
+	}
+	if int(props.IndexPartitions) != len(sst.BlockIndex) {
+		t.Errorf("Top-level index should have %d entries, got %d", props.IndexPartitions, len(sst.BlockIndex))
+	}
+
+	if _, ok := sst.filter.(*partitionedFilterReader); !ok {
+		t.Fatalf("Filter should be partitioned, got %T", sst.filter)
+	}
+	var name string
+	for _, ie := range sst.MetaIndex {
+		if strings.HasPrefix(string(ie.Key), partitionedFilterMetaPrefix) {
+			name = string(ie.Key)
+		}
+	}
+	if name != "partitionedfilter.leveldb.BuiltinBloomFilter2" {
+		t.Errorf("Meta index should contain the partitioned filter, got %v", sst.MetaIndex)
+	}
+
+	for i := 0; i < n; i++ {
+		value, err := table.Read(testKey(i))
+		if err != nil {
+			t.Fatalf("Looking for %s: %v", testKey(i), err)
+		}
+		if !bytes.Equal(value, testValue(i)) {
+			t.Errorf("Looking for %s, got %s", testKey(i), value)
+		}
+	}
+
+	var matches int
+	for i := 0; i < n - 1; i++ {
+		key := append(testKey(i), '.')
+
+		if sst.filter.keyMayMatch(0, key, false) {
+			matches++
+		}
+		if _, err := table.Read(key); err != ErrNotFound {
+			t.Errorf("Looking for %s, should be ErrNotFound got %v", key, err)
+		}
+	}
+	if matches > n / 50 {
+		t.Errorf("Too many false positives: %d in %d", matches, n)
+	}
+	if _, err := table.Read(Slice("zzz")); err != ErrNotFound {
+		t.Errorf("Looking for zzz, should be ErrNotFound got %v", err)
+	}
+}
+
+func TestPartitionedIndexIterator(t *testing.T) {
+	const n = 5000
+
+	table := openTestTable(t, n, partitionedOptions())
+	defer table.Close()
+
+	iter := table.Iterator()
+
+	var i int
+	for iter.Next() {
+		checkIterator(t, iter, i)
+		i++
+	}
+	if i != n {
+		t.Errorf("Should iterate over %d entries, got %d", n, i)
+	}
+
+	for iter.Prev() {
+		i--
+		checkIterator(t, iter, i)
+	}
+	if i != 0 {
+		t.Errorf("Should iterate back to 0, stopped at %d", i)
+	}
+
+	for _, i := range []int{0, 1, 999, 2500, n - 1} {
+		if !iter.Seek(testKey(i)) {
+			t.Fatalf("Seek %s should succeed", testKey(i))
+		}
+		checkIterator(t, iter, i)
+	}
+	if iter.Seek(Slice("zzz")) {
+		t.Error("Seek past the last key should fail")
+	}
+}
+
+func TestPartitionedIndexApproximateOffsetOf(t *testing.T) {
+	const n = 5000
+
+	table := openTestTable(t, n, partitionedOptions())
+	defer table.Close()
+
+	var last uint64
+	for i := 0; i < n; i += 100 {
+		offset := table.ApproximateOffsetOf(testKey(i))
+		if offset < last {
+			t.Errorf("Offset of %s should be at least %d, got %d", testKey(i), last, offset)
+		}
+		last = offset
+	}
+	if last == 0 {
+		t.Error("Offsets should grow with the keys")
+	}
+}
+
+func TestPartitionedIndexLayout(t *testing.T) {
+	const n = 5000
+
+	opt := partitionedOptions()
+	filename := writeTestTable(t, n, opt)
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	layout, err := ReadLayout(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layout.IndexPartitions) < 2 {
+		t.Errorf("Should have more than one index partition, got %d", len(layout.IndexPartitions))
+	}
+	if uint64(len(layout.BlockIndex)) != layout.Properties.NumDataBlocks {
+		t.Errorf("Layout should list %d data blocks, got %d", layout.Properties.NumDataBlocks, len(layout.BlockIndex))
+	}
+
+	report, err := Verify(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("Table should verify, got %v", report.Problems)
+	}
+	if report.NumEntries != n || uint64(report.NumDataBlocks) != layout.Properties.NumDataBlocks {
+		t.Errorf("Should verify %d entries in %d blocks, got %d in %d",
+			n, layout.Properties.NumDataBlocks, report.NumEntries, report.NumDataBlocks)
+	}
+}
+
+func TestIndexType(t *testing.T) {
+	opt := DefaultOptions()
+	opt.IndexType = IndexType(1)
+
+	if _, err := NewWriter(t.TempDir() + "/test.sst", opt); err != ErrTableIndexType {
+		t.Errorf("Should be ErrTableIndexType, got %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/entuerto/taigaDB/util"
+)
+
+const (
+	// Number of prefixes and keys per prefix of the prefix tables
+	numPrefixes   = 200
+	keysPerPrefix = 20
+)
+
+func prefixKey(p, i int) Slice {
+	return Slice(fmt.Sprintf("%04d.%04d", p, i))
+}
+
+// Writes the keys of the even prefixes.
+func writePrefixTable(t *testing.T, opt *Options) string {
+	filename := filepath.Join(t.TempDir(), "prefix.sst")
+
+	table, err := NewWriter(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for p := 0; p < numPrefixes; p += 2 {
+		for i := 0; i < keysPerPrefix; i++ {
+			if err := table.Write(prefixKey(p, i), testValue(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := table.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestPrefixIterator(t *testing.T) {
+	plain := DefaultOptions()
+	plain.FilterPolicy = util.BloomFilterPolicy(10)
+	plain.PrefixExtractor = util.FixedPrefixExtractor(5)
+
+	partitioned := partitionedOptions()
+	partitioned.PrefixExtractor = util.FixedPrefixExtractor(5)
+
+	for name, opt := range map[string]*Options{"plain": plain, "partitioned": partitioned} {
+		t.Run(name, func(t *testing.T) {
+			table, err := NewReader(writePrefixTable(t, opt), opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer table.Close()
+
+			if props := table.Properties(); props.PrefixExtractorName != "rocksdb.FixedPrefix.5" {
+				t.Errorf("Should record the prefix extractor, got %q", props.PrefixExtractorName)
+			}
+
+			sst  := table.(*ssTable)
+			iter := table.NewIterator(&ReadOptions{PrefixSameAsStart: true})
+
+			var matches int
+			for p := 0; p < numPrefixes; p++ {
+				prefix := prefixKey(p, 0)[:5]
+
+				if p % 2 == 1 {
+					if iter.Seek(prefix) {
+						t.Fatalf("Seek %s should not find keys, got %s", prefix, iter.Key())
+					}
+					if ok, _ := sst.prefixMayMatch(prefix, false); ok {
+						matches++
+					}
+					continue
+				}
+
+				var n int
+				for ok := iter.Seek(prefix); ok; ok = iter.Next() {
+					if !bytes.Equal(iter.Key(), prefixKey(p, n)) {
+						t.Fatalf("Should be %s, got %s", prefixKey(p, n), iter.Key())
+					}
+					n++
+				}
+				if n != keysPerPrefix {
+					t.Fatalf("Should find %d keys of %s, got %d", keysPerPrefix, prefix, n)
+				}
+
+				// Back from the end of the prefix
+				for iter.Prev() {
+					n--
+					if !bytes.Equal(iter.Key(), prefixKey(p, n)) {
+						t.Fatalf("Should be %s, got %s", prefixKey(p, n), iter.Key())
+					}
+				}
+				if n != 0 {
+					t.Fatalf("Should iterate back to the first key of %s, stopped at %d", prefix, n)
+				}
+			}
+			if matches > numPrefixes / 20 {
+				t.Errorf("Too many false positives: %d in %d", matches, numPrefixes / 2)
+			}
+			if err := iter.Error(); err != nil {
+				t.Error(err)
+			}
+
+			// Seek in the middle of a prefix
+			if !iter.Seek(prefixKey(10, 15)) {
+				t.Fatal("Seek in the middle of a prefix should succeed")
+			}
+			var n int
+			for ok := true; ok; ok = iter.Next() {
+				n++
+			}
+			if n != keysPerPrefix - 15 {
+				t.Errorf("Should find %d keys, got %d", keysPerPrefix - 15, n)
+			}
+
+			// Keys out of the extractor domain iterate over all the keys
+			n = 0
+			for ok := iter.Seek(Slice("0100")); ok; ok = iter.Next() {
+				n++
+			}
+			if n != numPrefixes / 4 * keysPerPrefix {
+				t.Errorf("Should find %d keys, got %d", numPrefixes / 4 * keysPerPrefix, n)
+			}
+		})
+	}
+}
+
+func TestPrefixIteratorOtherExtractor(t *testing.T) {
+	opt := DefaultOptions()
+	opt.FilterPolicy = util.BloomFilterPolicy(10)
+	opt.PrefixExtractor = util.FixedPrefixExtractor(5)
+
+	filename := writePrefixTable(t, opt)
+
+	// The filter has no prefixes of the extractor, the prefixes are still
+	// used to stop the iteration.
+	opt.PrefixExtractor = util.CappedPrefixExtractor(5)
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	sst := table.(*ssTable)
+	if sst.prefixFilter {
+		t.Error("Filter should not be used for the prefixes of another extractor")
+	}
+	if ok, err := sst.prefixMayMatch(Slice("0001."), false); !ok || err != nil {
+		t.Errorf("Prefixes should match without prefix filter, got %v %v", ok, err)
+	}
+
+	iter := table.NewIterator(&ReadOptions{PrefixSameAsStart: true})
+	if iter.Seek(Slice("0001.")) {
+		t.Errorf("Seek should not find keys, got %s", iter.Key())
+	}
+
+	var n int
+	for ok := iter.Seek(Slice("0002.")); ok; ok = iter.Next() {
+		n++
+	}
+	if n != keysPerPrefix {
+		t.Errorf("Should find %d keys, got %d", keysPerPrefix, n)
+	}
+
+	// Without the read option, iterators ignore the prefixes
+	n = 0
+	iter = table.Iterator()
+	for ok := iter.Seek(Slice("0002.")); ok; ok = iter.Next() {
+		n++
+	}
+	if n != (numPrefixes / 2 - 1) * keysPerPrefix {
+		t.Errorf("Should find %d keys, got %d", (numPrefixes / 2 - 1) * keysPerPrefix, n)
+	}
+}
+
+// With a reverse comparator, the prefix sorts after its keys and the index 
+// keys are full keys.
+func TestPrefixIteratorReverseComparator(t *testing.T) {
+	opt := DefaultOptions()
+	opt.Comparator = reverseComparator{}
+	opt.FilterPolicy = util.BloomFilterPolicy(10)
+	opt.PrefixExtractor = util.FixedPrefixExtractor(5)
+	opt.BlockSize = 256
+
+	filename := filepath.Join(t.TempDir(), "reverse.sst")
+
+	w, err := NewWriter(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := numPrefixes - 2; p >= 0; p -= 2 {
+		for i := keysPerPrefix - 1; i >= 0; i-- {
+			if err := w.Write(prefixKey(p, i), testValue(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	sst  := table.(*ssTable)
+	iter := table.NewIterator(&ReadOptions{PrefixSameAsStart: true})
+
+	var matches int
+	for p := 0; p < numPrefixes; p++ {
+		if p % 2 == 1 {
+			if ok, _ := sst.prefixMayMatch(prefixKey(p, keysPerPrefix - 1), false); ok {
+				matches++
+			}
+		}
+
+		var n int
+		for ok := iter.Seek(prefixKey(p, keysPerPrefix - 1)); ok; ok = iter.Next() {
+			if !bytes.Equal(iter.Key(), prefixKey(p, keysPerPrefix - 1 - n)) {
+				t.Fatalf("Should be %s, got %s", prefixKey(p, keysPerPrefix - 1 - n), iter.Key())
+			}
+			n++
+		}
+		if want := keysPerPrefix * (1 - p % 2); n != want {
+			t.Fatalf("Should find %d keys of prefix %d, got %d", want, p, n)
+		}
+	}
+	if matches > numPrefixes / 20 {
+		t.Errorf("Too many false positives: %d in %d", matches, numPrefixes / 2)
+	}
+	if err := iter.Error(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// Meta index key of the properties block
+	propertiesBlockName = "rocksdb.properties"
+
+	// Property names, the same as RocksDB when it has the property
+	propNumEntries      = "rocksdb.num.entries"
+	propRawKeySize      = "rocksdb.raw.key.size"
+	propRawValueSize    = "rocksdb.raw.value.size"
+	propDataSize        = "rocksdb.data.size"
+	propIndexSize       = "rocksdb.index.size"
+	propFilterSize      = "rocksdb.filter.size"
+	propNumDataBlocks   = "rocksdb.num.data.blocks"
+	propIndexPartitions = "rocksdb.index.partitions"
+	propComparator      = "rocksdb.comparator"
+	propCompression     = "rocksdb.compression"
+	propFilterPolicy    = "rocksdb.filter.policy"
+	propPrefixExtractor = "rocksdb.prefix.extractor.name"
+	propCreationTime    = "rocksdb.creation.time"
+	propSmallestKey     = "taigadb.smallest.key"
+	propLargestKey      = "taigadb.largest.key"
+)
+
+/*
+Properties Block Structure:
+
+	The properties block is a block whose entries map the property names 
+	to their values, as in RocksDB. Numbers are stored as varint64 and
+	names as strings. The block is never compressed.
+*/
+
+// Properties holds the statistics recorded by the writer of a table.
+type Properties struct {
+	// Number of entries in the table
+	NumEntries uint64
+	// Total size of the keys and of the values, before compression
+	RawKeySize   uint64
+	RawValueSize uint64
+
+	// Size of the data blocks, the index block and the filter block in the
+	// file, including the block trailers.
+	DataSize   uint64
+	IndexSize  uint64
+	FilterSize uint64
+
+	// Number of data blocks
+	NumDataBlocks uint64
+	// Number of index partitions, 0 if the index is not partitioned. The
+	// index and filter sizes include the partitions and the top-level index.
+	IndexPartitions uint64
+
+	// Name of the comparator, the compression and the filter policy the 
+	// table was written with. The filter policy is empty without filter.
+	ComparatorName   string
+	CompressionName  string
+	FilterPolicyName string
+
+	// Name of the prefix extractor whose prefixes are in the filter, empty
+	// if the filter has no prefixes.
+	PrefixExtractorName string
+
+	// Smallest and largest key in the table, nil if the table is empty
+	SmallestKey Slice
+	LargestKey  Slice
+
+	// Time the table was written at
+	CreationTime time.Time
+}
+
+func (self Properties) String() string {
+	return fmt.Sprintf("Properties { Entries: %d, Data blocks: %d, Data size: %d, Index size: %d, Filter size: %d, Comparator: %s, Compression: %s, Keys: [%q, %q]}",
+		self.NumEntries, self.NumDataBlocks, self.DataSize, self.IndexSize, self.FilterSize, 
+		self.ComparatorName, self.CompressionName, self.SmallestKey, self.LargestKey)
+}
+
+// Encodes the properties in a block sorted by name.
+func (self *Properties) encode() (Block, error) {
+	props := map[string]Slice{
+		propNumEntries:      encodeUvarint(self.NumEntries),
+		propRawKeySize:      encodeUvarint(self.RawKeySize),
+		propRawValueSize:    encodeUvarint(self.RawValueSize),
+		propDataSize:        encodeUvarint(self.DataSize),
+		propIndexSize:       encodeUvarint(self.IndexSize),
+		propFilterSize:      encodeUvarint(self.FilterSize),
+		propNumDataBlocks:   encodeUvarint(self.NumDataBlocks),
+		propIndexPartitions: encodeUvarint(self.IndexPartitions),
+		propComparator:      Slice(self.ComparatorName),
+		propCompression:     Slice(self.CompressionName),
+		propCreationTime:    encodeUvarint(uint64(self.CreationTime.Unix())),
+	}
+	if self.FilterPolicyName != "" {
+		props[propFilterPolicy] = Slice(self.FilterPolicyName)
+	}
+	if self.PrefixExtractorName != "" {
+		props[propPrefixExtractor] = Slice(self.PrefixExtractorName)
+	}
+	if self.NumEntries > 0 {
+		props[propSmallestKey] = self.SmallestKey
+		props[propLargestKey]  = self.LargestKey
+	}
+
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bb := NewBlockBuilder(1, nil)
+	for _, name := range names {
+		if err := bb.Add(Slice(name), props[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := bb.Finish(); err != nil {
+		return nil, err
+	}
+	return bb.Block(), nil
+}
+
+// Decodes the properties of a block. Unknown properties are ignored.
+func (self *Properties) decode(b Block) error {
+	iter := NewEntryIterator(b)
+	for entry, ok := iter.Next(); ok; entry, ok = iter.Next() {
+		value := entry.Value
+
+		switch string(entry.Key) {
+		case propNumEntries:
+			self.NumEntries = decodeUvarint(value)
+		case propRawKeySize:
+			self.RawKeySize = decodeUvarint(value)
+		case propRawValueSize:
+			self.RawValueSize = decodeUvarint(value)
+		case propDataSize:
+			self.DataSize = decodeUvarint(value)
+		case propIndexSize:
+			self.IndexSize = decodeUvarint(value)
+		case propFilterSize:
+			self.FilterSize = decodeUvarint(value)
+		case propNumDataBlocks:
+			self.NumDataBlocks = decodeUvarint(value)
+		case propIndexPartitions:
+			self.IndexPartitions = decodeUvarint(value)
+		case propComparator:
+			self.ComparatorName = string(value)
+		case propCompression:
+			self.CompressionName = string(value)
+		case propFilterPolicy:
+			self.FilterPolicyName = string(value)
+		case propPrefixExtractor:
+			self.PrefixExtractorName = string(value)
+		case propCreationTime:
+			self.CreationTime = time.Unix(int64(decodeUvarint(value)), 0)
+		case propSmallestKey:
+			self.SmallestKey = Slice(append([]byte(nil), value...))
+		case propLargestKey:
+			self.LargestKey = Slice(append([]byte(nil), value...))
+		}
+	}
+
+	return iter.Error()
+}
+
+func encodeUvarint(v uint64) Slice {
+	var buffer [binary.MaxVarintLen64]byte
+
+	n := binary.PutUvarint(buffer[:], v)
+	return Slice(append([]byte(nil), buffer[:n]...))
+}
+
+// Returns 0 for a malformed value.
+func decodeUvarint(b []byte) uint64 {
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,95 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"testing"
+	"time"
+
+	"github.com/entuerto/taigaDB/util"
+)
+
+func TestTableProperties(t *testing.T) {
+	const n = 2000
+
+	opt := DefaultOptions()
+	opt.FilterPolicy = util.BloomFilterPolicy(10)
+
+	start := time.Now().Add(-time.Second)
+
+	table := openTestTable(t, n, opt)
+	defer table.Close()
+
+	props := table.Properties()
+	if props == nil {
+		t.Fatal("Table should have properties")
+	}
+
+	var keySize, valueSize uint64
+	for i := 0; i < n; i++ {
+		keySize   += uint64(len(testKey(i)))
+		valueSize += uint64(len(testValue(i)))
+	}
+
+	if props.NumEntries != n {
+		t.Errorf("NumEntries: got %d, want %d", props.NumEntries, n)
+	}
+	if props.RawKeySize != keySize || props.RawValueSize != valueSize {
+		t.Errorf("Raw sizes: got %d/%d, want %d/%d", props.RawKeySize, props.RawValueSize, keySize, valueSize)
+	}
+
+	sst := table.(*ssTable)
+	if props.NumDataBlocks != uint64(len(sst.BlockIndex)) {
+		t.Errorf("NumDataBlocks: got %d, want %d", props.NumDataBlocks, len(sst.BlockIndex))
+	}
+	last := sst.BlockIndex[len(sst.BlockIndex) - 1].Handle
+	if props.DataSize != last.Offset + last.Size + BlockTrailerSize {
+		t.Errorf("DataSize: got %d, want %d", props.DataSize, last.Offset + last.Size + BlockTrailerSize)
+	}
+	if props.IndexSize != sst.BlockIndexHandle.Size + BlockTrailerSize {
+		t.Errorf("IndexSize: got %d, want %d", props.IndexSize, sst.BlockIndexHandle.Size + BlockTrailerSize)
+	}
+	if props.FilterSize == 0 || props.FilterPolicyName != "leveldb.BuiltinBloomFilter2" {
+		t.Errorf("Filter: got %d bytes of %q", props.FilterSize, props.FilterPolicyName)
+	}
+	if props.PrefixExtractorName != "" {
+		t.Errorf("PrefixExtractorName: got %q, want none", props.PrefixExtractorName)
+	}
+
+	if props.ComparatorName != "leveldb.BytewiseComparator" || props.CompressionName != "NoCompression" {
+		t.Errorf("Names: got %q and %q", props.ComparatorName, props.CompressionName)
+	}
+	if string(props.SmallestKey) != string(testKey(0)) || string(props.LargestKey) != string(testKey(n - 1)) {
+		t.Errorf("Key range: got [%s, %s]", props.SmallestKey, props.LargestKey)
+	}
+	if props.CreationTime.Before(start) || props.CreationTime.After(time.Now()) {
+		t.Errorf("CreationTime: got %v", props.CreationTime)
+	}
+}
+
+func TestTablePropertiesEmpty(t *testing.T) {
+	table := openTestTable(t, 0, DefaultOptions())
+	defer table.Close()
+
+	props := table.Properties()
+	if props == nil {
+		t.Fatal("Table should have properties")
+	}
+	if props.NumEntries != 0 || props.NumDataBlocks != 0 || props.SmallestKey != nil || props.LargestKey != nil {
+		t.Errorf("Empty table: got %v", props)
+	}
+}
+
+func TestTablePropertiesLevelDB(t *testing.T) {
+	table, err := NewReader("../data/h.no-compression.sst", DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	if props := table.Properties(); props != nil {
+		t.Errorf("LevelDB tables have no properties, got %v", props)
+	}
+}
@@ -27,6 +27,17 @@ type Table interface {
 type TableReader interface {
 	Table
 	Reader
+
+	// Look for a key/value in the table with the given read options.
+	Get(key Slice, ro *ReadOptions) (Slice, error)
+
+	// Returns an iterator over the table contents with the given read 
+	// options.
+	NewIterator(ro *ReadOptions) Iterator
+
+	// Returns the properties recorded by the writer of the table, nil if 
+	// the table has none, like the tables written by LevelDB.
+	Properties() *Properties
 }
 
 type TableWriter interface {
@@ -0,0 +1,206 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/entuerto/taigaDB/cache"
+)
+
+// ReaderAt returning io.EOF along with the last bytes of the table.
+type eofReaderAt struct {
+	data []byte
+}
+
+func (self eofReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	if off >= int64(len(self.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, self.data[off:])
+	if off + int64(n) == int64(len(self.data)) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func TestReaderAt(t *testing.T) {
+	data, err := os.ReadFile("../data/h.no-compression.sst")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []io.ReaderAt{bytes.NewReader(data), eofReaderAt{data}} {
+		table, err := NewReaderAt(r, int64(len(data)), DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		value, err := table.Read(Slice("school"))
+		if err != nil || string(value) != "1" {
+			t.Errorf("Looking for school, got %s %v", value, err)
+		}
+
+		if err := table.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestReaderAtTruncated(t *testing.T) {
+	data, err := os.ReadFile("../data/h.no-compression.sst")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewReaderAt(bytes.NewReader(data[:20]), 20, nil); err != ErrTableMagicNumber {
+		t.Errorf("Should be ErrTableMagicNumber, got %v", err)
+	}
+
+	// The footer points past the end of the reader
+	r := bytes.NewReader(data[len(data) - 100:])
+	if _, err := NewReaderAt(r, int64(len(data)), nil); err != ErrBlockReadCorruption {
+		t.Errorf("Should be ErrBlockReadCorruption, got %v", err)
+	}
+}
+
+func TestReaderAtCorruptHandles(t *testing.T) {
+	data, err := os.ReadFile("../data/h.no-compression.sst")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		bh   *BlockHandle
+	}{
+		{"too large", NewHandle(0, 1 << 62)},
+		{"size overflow", NewHandle(16, math.MaxUint64 - 2)},
+		{"offset overflow", NewHandle(math.MaxUint64 - 2, 8)},
+		{"past the end", NewHandle(uint64(len(data)) - 8, 8)},
+	}
+	for _, tc := range testCases {
+		for _, meta := range []bool{true, false} {
+			index := NewHandle(0, 8)
+			metaIndex := tc.bh
+			if !meta {
+				metaIndex, index = index, tc.bh
+			}
+
+			corrupt := fuzzFooter(data, metaIndex, index)
+			if _, err := NewReaderAt(bytes.NewReader(corrupt), int64(len(corrupt)), nil); err == nil {
+				t.Errorf("%s: should fail with %v", tc.name, tc.bh)
+			}
+
+			sst := &ssTable{size: int64(len(data))}
+			if sst.inTable(tc.bh) {
+				t.Errorf("%s: %v should not be in the table", tc.name, tc.bh)
+			}
+		}
+	}
+}
+
+func TestStreamWriter(t *testing.T) {
+	const n = 1000
+
+	var buf bytes.Buffer
+
+	w, err := NewStreamWriter(&buf, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := w.Write(testKey(i), testValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := NewReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	iter := table.Iterator()
+	for i := 0; i < n; i++ {
+		if !iter.Next() {
+			t.Fatalf("Iterator stopped at %d: %v", i, iter.Error())
+		}
+		checkIterator(t, iter, i)
+	}
+}
+
+func TestMmapReader(t *testing.T) {
+	const n = 2000
+
+	opt := DefaultOptions()
+	opt.UseMmap = true
+	opt.BlockCache = cache.NewLRU(1 << 20)
+
+	filename := writeTestTable(t, n, opt)
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sst := table.(*ssTable)
+	if sst.mapped == nil {
+		t.Skip("Memory mapped files are not supported")
+	}
+
+	for i := 0; i < n; i++ {
+		if value, err := table.Read(testKey(i)); err != nil || !bytes.Equal(value, testValue(i)) {
+			t.Fatalf("Looking for %s, got %s %v", testKey(i), value, err)
+		}
+	}
+
+	block, err := sst.readDataBlock(&sst.BlockIndex[0].Handle, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sst.inMapping(block, &sst.BlockIndex[0].Handle) {
+		t.Error("Uncompressed block should be a slice of the mapping")
+	}
+	if stats := opt.BlockCache.Stats(); stats.Entries != 0 {
+		t.Errorf("Mapped blocks should not be cached, got %+v", stats)
+	}
+
+	if err := table.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMmapCompressed(t *testing.T) {
+	compressible := func(i int) Slice {
+		return bytes.Repeat(testValue(i), 10)
+	}
+
+	opt := DefaultOptions()
+	opt.UseMmap = true
+	opt.Compression = SnappyCompression
+
+	filename := writeValuesTable(t, 500, opt, compressible)
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	iter := table.Iterator()
+	for i := 0; i < 500; i++ {
+		if !iter.Next() || !bytes.Equal(iter.Value(), compressible(i)) {
+			t.Fatalf("Entry %d does not match: %v", i, iter.Error())
+		}
+	}
+}
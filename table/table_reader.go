@@ -5,31 +5,84 @@
 package table
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
+	"sync"
 
-	"github.com/entuerto/taigaDB/util"
-	"code.google.com/p/snappy-go/snappy"
+	"github.com/entuerto/taigaDB/cache"
 )
 
 func NewReader(filename string, opt *Options) (TableReader, error) {
-	var table = &ssTable{
-		options: opt,
-	}
-
 	// Read only
 	file, err := os.Open(filename) 
 	if err != nil {
 		return nil, err
 	}
-	table.file = file
+
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if opt != nil && opt.UseMmap && fi.Size() >= FooterEncodedLength {
+		if mapped, err := mmap(file, fi.Size()); err == nil {
+			// The mapping stays valid after the file is closed.
+			file.Close()
+			return newMappedReader(mapped, opt)
+		}
+	}
+
+	table, err := newReader(file, fi.Size(), opt)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	table.closer = file
+
+	return table, nil
+}
+
+func newMappedReader(mapped []byte, opt *Options) (TableReader, error) {
+	table, err := newReader(bytes.NewReader(mapped), int64(len(mapped)), opt)
+	if err != nil {
+		munmap(mapped)
+		return nil, err
+	}
+	table.mapped = mapped
+	table.closer = mapping(mapped)
+
+	return table, nil
+}
+
+// NewReaderAt returns a reader of the table stored in the first size bytes 
+// of r. Closing the table does not close r.
+func NewReaderAt(r io.ReaderAt, size int64, opt *Options) (TableReader, error) {
+	return newReader(r, size, opt)
+}
+
+func newReader(r io.ReaderAt, size int64, opt *Options) (*ssTable, error) {
+	var table = &ssTable{
+		reader: r,
+		size: size,
+		options: opt,
+	}
 
 	if table.options == nil {
 		table.options = DefaultOptions()
 	}
 
-	if err = table.readFooter(); err != nil {
+	if table.options.BlockCache != nil {
+		if table.cacheID = table.options.FileNumber; table.cacheID == 0 {
+			table.cacheID = table.options.BlockCache.NewID()
+			table.cached  = new(cachedBlocks)
+		}
+	}
+
+	if err := table.readFooter(); err != nil {
 		return nil, err
 	}
 
@@ -37,38 +90,106 @@ func NewReader(filename string, opt *Options) (TableReader, error) {
 	if metaBlock, err := table.readBlock(table.MetaIndexHandle); err != nil {
 		return nil, err
 	} else {
-		table.MetaIndex = decodeIndexEntries(metaBlock)
+		if table.MetaIndex, err = decodeIndexEntries(metaBlock); err != nil {
+			return nil, err
+		}
 	}
 
-	if err = table.readFilter(); err != nil {
+	if err := table.readProperties(); err != nil {
 		return nil, err
 	}
 
-	// Read the index block
+	if err := table.readFilter(); err != nil {
+		return nil, err
+	}
+
+	// Read the index block, or the top-level index of the partitions
 	if indexBlock, err := table.readBlock(table.BlockIndexHandle); err != nil {
 		return nil, err
 	} else {
-		table.BlockIndex = decodeIndexEntries(indexBlock)
+		if table.BlockIndex, err = decodeIndexEntries(indexBlock); err != nil {
+			return nil, err
+		}
 	}
+	table.partitioned = table.properties != nil && table.properties.IndexPartitions > 0
 
 	return table, nil
 }
 
+// Offsets of the blocks inserted in the block cache by a reader.
+type cachedBlocks struct {
+	mu      sync.Mutex
+	offsets map[uint64]struct{}
+}
+
+func (self *cachedBlocks) add(offset uint64) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.offsets == nil {
+		self.offsets = make(map[uint64]struct{})
+	}
+	self.offsets[offset] = struct{}{}
+}
+
+func (self *cachedBlocks) erase(c *cache.LRU, id uint64) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	for offset := range self.offsets {
+		c.Erase(cache.Key{ID: id, Offset: offset})
+	}
+	self.offsets = nil
+}
+
+// Unmaps the table contents on Close.
+type mapping []byte
+
+func (self mapping) Close() error {
+	return munmap(self)
+}
+
 //---------------------------------------------------------------------------------------
 // Sorted String Table
 //---------------------------------------------------------------------------------------
 
 type ssTable struct {
-	file *os.File
+	reader io.ReaderAt
+	size   int64
+	// Nil if the reader is not owned by the table
+	closer io.Closer
+	// Table contents when the file is memory mapped
+	mapped []byte
 
 	options *Options
 
+	footer Footer
+
 	// Handles to the specified file location
 	MetaIndexHandle  *BlockHandle
 	BlockIndexHandle *BlockHandle
 
+	// Checksum of the block trailers
+	checksum ChecksumType
+
+	// Key space of the table in the block cache
+	cacheID uint64
+	// Blocks erased from the cache on Close, nil if the blocks are shared
+	// by the readers of the file number
+	cached *cachedBlocks
+
 	MetaIndex  IndexSlice
+	// With a two-level index, the top-level index of the partitions
 	BlockIndex IndexSlice
+	partitioned bool
+
+	// Nil if the table has no filter for the filter policy
+	filter filterReader
+	// Whether the filter has the prefixes of the prefix extractor
+	prefixFilter bool
+
+	// Properties of the table, nil if the table has none
+	properties *Properties
 }
 
 func (self ssTable) String() string {
@@ -76,67 +197,195 @@ func (self ssTable) String() string {
 }
 
 func (self *ssTable) Iterator() Iterator {
-	return &ssTableIterator{
-		sst: self,
-		idx: self.BlockIndex,
+	return self.NewIterator(nil)
+}
+
+func (self *ssTable) NewIterator(ro *ReadOptions) Iterator {
+	verify := self.verifyChecksums(ro)
+
+	iter := newTwoLevelIterator(self.newBlockIndexIterator(verify), func(value Slice) (Iterator, error) {
+		return self.newBlockIterator(value, verify)
+	})
+
+	if ro != nil && ro.PrefixSameAsStart && self.options.PrefixExtractor != nil {
+		return newPrefixIterator(iter, self.options.PrefixExtractor, func(key Slice) (bool, error) {
+			return self.prefixMayMatch(key, verify)
+		})
+	}
+	return iter
+}
+
+// Returns false if the filter tells the table has no key >= key with the
+// prefix of key, which must be in the extractor domain. The data blocks that
+// may hold these keys are the ones from the first block with an index key >=
+// key to the first block with an index key without the prefix.
+func (self *ssTable) prefixMayMatch(key Slice, verify bool) (bool, error) {
+	if self.filter == nil || !self.prefixFilter {
+		return true, nil
+	}
+
+	ext    := self.options.PrefixExtractor
+	prefix := ext.Transform(key)
+
+	index := self.newBlockIndexIterator(verify)
+	for ok := index.Seek(key); ok; ok = index.Next() {
+		var handle BlockHandle
+		if _, err := handle.Decode(index.Value()); err != nil {
+			return true, err
+		}
+
+		if self.filter.prefixMayMatch(handle.Offset, index.Key(), prefix, verify) {
+			return true, nil
+		}
+		if key := index.Key(); !ext.InDomain(key) || !bytes.Equal(ext.Transform(key), prefix) {
+			break
+		}
+	}
+	return false, index.Error()
+}
+
+// Returns an iterator over the index entries of the data blocks. With a 
+// two-level index, the partitions are read through the block cache.
+func (self *ssTable) newBlockIndexIterator(verify bool) Iterator {
+	index := newIndexIterator(self.BlockIndex, self.options.Comparator)
+	if !self.partitioned {
+		return index
+	}
+
+	return newTwoLevelIterator(index, func(value Slice) (Iterator, error) {
+		return self.newBlockIterator(value, verify)
+	})
+}
+
+// Returns an iterator over the block of an encoded handle.
+func (self *ssTable) newBlockIterator(value Slice, verify bool) (Iterator, error) {
+	var handle BlockHandle
+
+	if _, err := handle.Decode(value); err != nil {
+		return nil, err
+	}
+
+	block, err := self.readDataBlock(&handle, verify)
+	if err != nil {
+		return nil, err
+	}
+	return newBlockIterator(block, self.options.Comparator), nil
+}
+
+// Returns the handle of the first data block with keys >= key, false if
+// key is after the last key of the table.
+func (self *ssTable) findBlock(key Slice, verify bool) (BlockHandle, bool, error) {
+	var handle BlockHandle
+
+	index := self.newBlockIndexIterator(verify)
+	if !index.Seek(key) {
+		return handle, false, index.Error()
 	}
+
+	if _, err := handle.Decode(index.Value()); err != nil {
+		return handle, false, err
+	}
+	return handle, true, nil
 }
 
 func (self *ssTable) ApproximateOffsetOf(key Slice) uint64 {
-	return uint64(0)
+	if handle, ok, _ := self.findBlock(key, false); ok {
+		return handle.Offset
+	}
+
+	// The key is past the last key in the file. Approximate the offset by 
+	// returning the offset of the meta index block, which is right near 
+	// the end of the file.
+	return self.MetaIndexHandle.Offset
+}
+
+func (self *ssTable) Properties() *Properties {
+	return self.properties
 }
 
-func (self ssTable) Read(key Slice) (Slice, error) {
-	//
-	i := self.BlockIndex.Search(key)
-	if i == self.BlockIndex.Len() {
+func (self *ssTable) Read(key Slice) (Slice, error) {
+	return self.Get(key, nil)
+}
+
+func (self *ssTable) Get(key Slice, ro *ReadOptions) (Slice, error) {
+	verify := self.verifyChecksums(ro)
+
+	handle, ok, err := self.findBlock(key, verify)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return nil, ErrNotFound
 	}
 	
-	blockIdx := self.BlockIndex[i]
-	block, err := self.readBlock(&blockIdx.Handle)
+	if self.filter != nil && !self.filter.keyMayMatch(handle.Offset, key, verify) {
+		return nil, ErrNotFound
+	}
+
+	block, err := self.readDataBlock(&handle, verify)
 	if err != nil {
 		return nil, err
 	}
 
-	entry := block.Search(key, self.options.Comparator)
+	entry, err := block.Search(key, self.options.Comparator)
+	if err != nil {
+		return nil, err
+	}
 	if entry != nil {
 		return entry.Value, nil
 	}	
 	return nil, ErrNotFound
 }
 
-func (self ssTable) Close() error {
-	return self.file.Close()
+func (self *ssTable) Close() error {
+	if self.cached != nil {
+		self.cached.erase(self.options.BlockCache, self.cacheID)
+	}
+	if self.closer != nil {
+		return self.closer.Close()
+	}
+	return nil
 }
 
 func (self *ssTable) readFooter() error {
-	var offset int64
+	size := self.size
 
-	if fi, err := self.file.Stat(); err != nil {
-		return err
-	} else {
-		offset = fi.Size() - FooterEncodedLength
+	if size < FooterEncodedLength {
+		return ErrTableMagicNumber
 	}
 
-	var buffer [FooterEncodedLength]byte
+	// Large enough for both footer formats
+	var buffer [VersionedFooterEncodedLength]byte
 
-	if n, err := self.file.ReadAt(buffer[0:], offset); err != nil || n != FooterEncodedLength {
+	length := int64(len(buffer))
+	if size < length {
+		length = size
+	}
+
+	if err := self.readAt(buffer[:length], size - length); err != nil {
 		return err
 	}
 
 	var footer Footer
 
-	if _, err := footer.Decode(buffer[0:]); err != nil {
+	if _, err := footer.Decode(buffer[:length]); err != nil {
 		return err
 	}
 
-	if footer.MagicNumber != TableMagicNumber {
+	if footer.MagicNumber != TableMagicNumber && footer.MagicNumber != VersionedTableMagicNumber {
 		return ErrTableMagicNumber
 	}
 
+	switch footer.ChecksumType {
+	case NoChecksum, CRC32CChecksum, XXHash64Checksum:
+	default:
+		return ErrTableChecksumType
+	}
+
+	self.footer           = footer
 	self.MetaIndexHandle  = footer.MetaIndexHandle
 	self.BlockIndexHandle = footer.BlockIndexHandle
+	self.checksum         = footer.ChecksumType
 
 	return nil
 }
@@ -159,40 +408,196 @@ func (self ssTable) Dump() {
 	}
 }
 
+// Reads the properties block and checks the table was written with the 
+// comparator of the options. Tables without properties, like the LevelDB 
+// tables, are accepted with any comparator.
+func (self *ssTable) readProperties() error {
+	handle, ok := self.findMetaBlock(propertiesBlockName)
+	if !ok {
+		return nil
+	}
+
+	block, err := self.readBlock(handle)
+	if err != nil {
+		return err
+	}
+	self.properties = new(Properties)
+	if err := self.properties.decode(block); err != nil {
+		return err
+	}
+
+	if cmp := self.properties.ComparatorName; cmp != "" && cmp != self.options.Comparator.Name() {
+		return ErrTableComparator
+	}
+
+	return nil
+}
+
 func (self *ssTable) readFilter() error {
+	policy := self.options.FilterPolicy
+	if policy == nil {
+		return nil
+	}
+
+	if prefix := self.options.PrefixExtractor; prefix != nil && self.properties != nil {
+		self.prefixFilter = self.properties.PrefixExtractorName == prefix.Name()
+	}
+
+	if handle, ok := self.findMetaBlock(filterMetaPrefix + policy.Name()); ok {
+		block, err := self.readBlock(handle)
+		if err != nil {
+			return err
+		}
+		if filter := newFilterBlockReader(policy, block); filter != nil {
+			self.filter = filter
+		}
+		return nil
+	}
+
+	if handle, ok := self.findMetaBlock(partitionedFilterMetaPrefix + policy.Name()); ok {
+		block, err := self.readBlock(handle)
+		if err != nil {
+			return err
+		}
+		top, err := decodeIndexEntries(block)
+		if err != nil {
+			return err
+		}
+		self.filter = newPartitionedFilterReader(self, policy, top)
+	}
+
+	// Tables written without a filter, or with another policy.
 	return nil
 }
 
-func (self *ssTable) readBlock(bh *BlockHandle) (Block, error) {
-	var buffer = make([]byte, bh.Size + BlockTrailerSize)
+// Returns the handle of the meta block with the given name.
+func (self *ssTable) findMetaBlock(name string) (*BlockHandle, bool) {
+	i := self.MetaIndex.Search(Slice(name), nil)
+	if i == self.MetaIndex.Len() || string(self.MetaIndex[i].Key) != name {
+		return nil, false
+	}
+	return &self.MetaIndex[i].Handle, true
+}
+
+// Returns whether the block and its trailer are in the table. The handles 
+// of a corrupt table may be past the end of the table, or overflow.
+func (self *ssTable) inTable(bh *BlockHandle) bool {
+	size := uint64(self.size)
+	if self.size < 0 || bh.Size > size || size - bh.Size < BlockTrailerSize {
+		return false
+	}
+	return bh.Offset <= size - bh.Size - BlockTrailerSize
+}
+
+// Returns whether the block is a slice of the memory mapped table.
+func (self *ssTable) inMapping(b Block, bh *BlockHandle) bool {
+	return self.mapped != nil && len(b) > 0 && &b[0] == &self.mapped[bh.Offset]
+}
+
+// Reads len(b) bytes at offset. A short read is a corruption, the handles 
+// point past the end of the table.
+func (self *ssTable) readAt(b []byte, offset int64) error {
+	n, err := self.reader.ReadAt(b, offset)
+	if n == len(b) {
+		// io.ReaderAt may return io.EOF with a full read at the end
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		return ErrBlockReadCorruption
+	}
+	return err
+}
+
+// Returns whether the checksums are verified with the read options.
+func (self *ssTable) verifyChecksums(ro *ReadOptions) bool {
+	if ro != nil {
+		switch ro.VerifyChecksums {
+		case VerifyChecksumsOn:
+			return true
+		case VerifyChecksumsOff:
+			return false
+		}
+	}
+	return self.options.VerifyChecksums
+}
 
-	n, err := self.file.ReadAt(buffer[:], int64(bh.Offset))
+// Reads a data block, or an index or filter partition, through the block 
+// cache.
+func (self *ssTable) readDataBlock(bh *BlockHandle, verify bool) (Block, error) {
+	blockCache := self.options.BlockCache
+	if blockCache == nil {
+		return self.readBlockVerify(bh, verify)
+	}
+
+	key := cache.Key{ID: self.cacheID, Offset: bh.Offset}
+	if b, ok := blockCache.Get(key); ok {
+		return Block(b), nil
+	}
+
+	b, err := self.readBlockVerify(bh, verify)
 	if err != nil {
 		return nil, err
 	}
-	if n != int(bh.Size + BlockTrailerSize) {
-		return nil, ErrBlockReadCorruption
+
+	// Blocks in the mapping are already in memory, and must not outlive 
+	// the table.
+	if !self.inMapping(b, bh) {
+		blockCache.Insert(key, b)
+		if self.cached != nil {
+			self.cached.add(bh.Offset)
+		}
 	}
 
-	// Checksum from block trailer
-	checksum1 := binary.LittleEndian.Uint32(buffer[bh.Size + 1:])
-	// Checksum calculated from block buffer
-	checksum2 := util.Checksum32(buffer[:bh.Size + 1])
+	return b, nil
+}
+
+func (self *ssTable) readBlock(bh *BlockHandle) (Block, error) {
+	return self.readBlockVerify(bh, self.options.VerifyChecksums)
+}
+
+// Reads a block, the trailer checksum is verified only if verify is true.
+// Uncompressed blocks of a memory mapped table are not copied.
+func (self *ssTable) readBlockVerify(bh *BlockHandle, verify bool) (Block, error) {
+	var buffer []byte
 
-	if checksum1 != checksum2 {
-		return nil, ErrBlockCRC32Corruption
+	if !self.inTable(bh) {
+		return nil, ErrBlockReadCorruption
 	}
 
-	switch Compression(buffer[bh.Size]) {
-	case NoCompression:
-		return buffer[:bh.Size], nil
-	case SnappyCompression:
-		b, err := snappy.Decode(nil, buffer[:bh.Size])
-		if err != nil {
+	if self.mapped != nil {
+		buffer = self.mapped[bh.Offset:bh.Offset + bh.Size + BlockTrailerSize]
+	} else {
+		buffer = make([]byte, bh.Size + BlockTrailerSize)
+
+		if err := self.readAt(buffer, int64(bh.Offset)); err != nil {
 			return nil, err
 		}
-		return b, nil
 	}
 
-	return nil, ErrTableBlockCompression
-}
+	if verify && self.checksum != NoChecksum {
+		// Checksum from block trailer
+		checksum1 := binary.LittleEndian.Uint32(buffer[bh.Size + 1:])
+		// Checksum calculated from block buffer
+		checksum2 := blockChecksum(self.checksum, buffer[:bh.Size + 1])
+
+		if checksum1 != checksum2 {
+			return nil, ErrBlockCRC32Corruption
+		}
+	}
+
+	compression := Compression(buffer[bh.Size])
+	if compression == NoCompression {
+		return buffer[:bh.Size], nil
+	}
+
+	codec, ok := LookupCodec(compression)
+	if !ok {
+		return nil, ErrTableBlockCompression
+	}
+
+	b, err := codec.Decode(nil, buffer[:bh.Size])
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
\ No newline at end of file
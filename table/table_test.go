@@ -7,6 +7,8 @@ package table
 import (
 	_ "fmt"
 	"testing"
+
+	"github.com/entuerto/taigaDB/cache"
 )
 
 
@@ -67,3 +69,129 @@ func BenchmarkEntryIterator(b *testing.B) {
 	}
 	
 }
+
+func TestApproximateOffsetOf(t *testing.T) {
+	table, err := NewReader("../data/h.no-compression.sst", DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	sst := table.(*ssTable)
+
+	if offset := table.ApproximateOffsetOf(Slice("")); offset != 0 {
+		t.Errorf("Offset of the first key should be 0, got %d", offset)
+	}
+
+	if offset := table.ApproximateOffsetOf(Slice("\xff")); offset != sst.MetaIndexHandle.Offset {
+		t.Errorf("Offset past the last key should be %d, got %d", sst.MetaIndexHandle.Offset, offset)
+	}
+
+	for _, idx := range sst.BlockIndex {
+		if offset := table.ApproximateOffsetOf(idx.Key); offset != idx.Handle.Offset {
+			t.Errorf("Offset of %s should be %d, got %d", idx.Key, idx.Handle.Offset, offset)
+		}
+	}
+}
+
+func TestBlockCache(t *testing.T) {
+	opt := DefaultOptions()
+	opt.BlockCache = cache.NewLRU(1 << 20)
+
+	table1, err := NewReader("../data/h.no-compression.sst", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table1.Close()
+
+	for i := 0; i < 10; i++ {
+		if value, err := table1.Read(Slice("school")); err != nil || string(value) != "1" {
+			t.Fatalf("Looking for school, got %s %v", value, err)
+		}
+	}
+
+	if stats := opt.BlockCache.Stats(); stats.Hits != 9 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("Should be 9 hits, 1 miss and 1 entry, got %+v", stats)
+	}
+
+	// Tables without file number do not share blocks
+	table2, err := NewReader("../data/h.no-compression.sst", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := table2.Read(Slice("school")); err != nil {
+		t.Fatal(err)
+	}
+
+	iter := table2.Iterator()
+	for iter.Next() {
+	}
+
+	sst := table2.(*ssTable)
+	if stats := opt.BlockCache.Stats(); stats.Entries != 1 + len(sst.BlockIndex) {
+		t.Errorf("Should be %d entries, got %+v", 1 + len(sst.BlockIndex), stats)
+	}
+
+	// Their blocks are erased on Close
+	table2.Close()
+	if stats := opt.BlockCache.Stats(); stats.Entries != 1 {
+		t.Errorf("Should be 1 entry, got %+v", stats)
+	}
+}
+
+func TestBlockCacheFileNumber(t *testing.T) {
+	opt := DefaultOptions()
+	opt.BlockCache = cache.NewLRU(1 << 20)
+	opt.FileNumber = 7
+
+	// Readers of the same file number share the blocks, which outlive the
+	// readers
+	for i := 0; i < 3; i++ {
+		table, err := NewReader("../data/h.no-compression.sst", opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value, err := table.Read(Slice("school")); err != nil || string(value) != "1" {
+			t.Fatalf("Looking for school, got %s %v", value, err)
+		}
+		table.Close()
+	}
+
+	if stats := opt.BlockCache.Stats(); stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("Should be 2 hits, 1 miss and 1 entry, got %+v", stats)
+	}
+
+	// Another file number has its own blocks
+	opt.FileNumber = 8
+	table, err := NewReader("../data/h.no-compression.sst", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	if _, err := table.Read(Slice("school")); err != nil {
+		t.Fatal(err)
+	}
+	if stats := opt.BlockCache.Stats(); stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("Should be 2 misses and 2 entries, got %+v", stats)
+	}
+}
+
+func BenchmarkCachedLookup(b *testing.B) {
+	opt := DefaultOptions()
+	opt.BlockCache = cache.NewLRU(1 << 20)
+
+	table, err := NewReader("../data/h.no-compression.sst", opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer table.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := table.Read(Slice("school")); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
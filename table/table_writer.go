@@ -5,30 +5,102 @@
 package table
 
 import (
-	_ "encoding/binary"
-	_ "fmt"
+	"encoding/binary"
+	"io"
 	"os"
+	"time"
 
-	_ "github.com/entuerto/taigaDB/util"
-	_ "code.google.com/p/snappy-go/snappy"
+	"github.com/entuerto/taigaDB/util"
 )
 
 func NewWriter(filename string, opt *Options) (TableWriter, error) {
-	var table = &ssTableWriter{
-		options: opt,
+	table, err := newWriter(opt)
+	if err != nil {
+		return nil, err
 	}
 
-	// Read only
+	// Write only
 	file, err := os.Create(filename) 
 	if err != nil {
 		return nil, err
 	}
-	table.file = file
+	table.writer = file
+	table.closer = file
+
+	return table, nil
+}
+
+// NewStreamWriter returns a writer of a table to w. Closing the table 
+// writes the end of the table but does not close w.
+func NewStreamWriter(w io.Writer, opt *Options) (TableWriter, error) {
+	table, err := newWriter(opt)
+	if err != nil {
+		return nil, err
+	}
+	table.writer = w
+
+	return table, nil
+}
+
+func newWriter(opt *Options) (*ssTableWriter, error) {
+	var table = &ssTableWriter{
+		options: opt,
+	}
 
 	if table.options == nil {
 		table.options = DefaultOptions()
 	}
 
+	if table.options.Compression != NoCompression {
+		codec, ok := LookupCodec(table.options.Compression)
+		if !ok {
+			return nil, ErrTableBlockCompression
+		}
+		table.codec = codec
+	}
+
+	// An unset checksum is CRC32C
+	table.checksum = table.options.Checksum
+	if table.checksum == 0 {
+		table.checksum = CRC32CChecksum
+	}
+
+	switch table.checksum {
+	case NoChecksum, CRC32CChecksum, XXHash64Checksum:
+	default:
+		return nil, ErrTableChecksumType
+	}
+
+	switch table.options.IndexType {
+	case BinarySearchIndex:
+	case TwoLevelIndex:
+		table.partitionIndex   = true
+		table.partitionFilters = table.options.PartitionFilters
+	default:
+		return nil, ErrTableIndexType
+	}
+
+	table.dataBlock  = NewBlockBuilder(table.options.BlockRestartInterval, table.options.Comparator)
+	table.indexBlock = NewBlockBuilder(1, table.options.Comparator)
+	table.metaBlock  = NewBlockBuilder(1, util.BytewiseComparator{})
+
+	switch table.options.DataBlockIndexType {
+	case DataBlockBinarySearch:
+	case DataBlockBinaryAndHash:
+		table.dataBlock.EnableHashIndex(table.options.DataBlockHashTableUtilRatio)
+	default:
+		return nil, ErrTableDataBlockIndex
+	}
+
+	if table.options.FilterPolicy != nil {
+		table.filterBlock = newFilterBlockBuilder(table.options.FilterPolicy, table.options.PrefixExtractor)
+		if !table.partitionFilters {
+			table.filterBlock.startBlock(0)
+		}
+	} else {
+		table.partitionFilters = false
+	}
+
 	return table, nil
 }
 
@@ -37,16 +109,376 @@ func NewWriter(filename string, opt *Options) (TableWriter, error) {
 //---------------------------------------------------------------------------------------
 
 type ssTableWriter struct {
-	file *os.File
+	writer io.Writer
+	// Nil if the writer is not owned by the table
+	closer io.Closer
 
 	options *Options
+	// Checksum of the block trailers
+	checksum ChecksumType
+
+	// Current file offset, where the next block will be written
+	offset uint64
+
+	dataBlock  *BlockBuilder
+	indexBlock *BlockBuilder
+	metaBlock  *BlockBuilder
+
+	// Nil if no filter policy is set
+	filterBlock *filterBlockBuilder
+
+	// Nil if blocks are not compressed
+	codec Codec
+	// Buffer reused to compress blocks
+	compressed []byte
+
+	// Last key added to the table
+	lastKey Slice
+	// Statistics of the table, written in the properties block
+	props Properties
+
+	// The index entry for a data block is only added when the first key of
+	// the next block is known, or when the table is closed.
+	pendingIndexEntry bool
+	pendingHandle     BlockHandle
+
+	// With a two-level index, the finished index partitions and their last 
+	// key, and the filter of each partition if the filter is partitioned.
+	// The partitions are written when the table is closed.
+	partitionIndex   bool
+	partitionFilters bool
+	partitions       []partition
+
+	closed bool
+	err    error
 }
 
-func (self ssTableWriter) Write(key, value Slice) error {
-	
+func (self *ssTableWriter) Write(key, value Slice) error {
+	if self.closed {
+		return ErrTableWriterClosed
+	}
+	if self.err != nil {
+		return self.err
+	}
+
+	if self.props.NumEntries > 0 && self.options.Comparator.Compare(key, self.lastKey) <= 0 {
+		return ErrTableKeyOrder
+	}
+
+	if self.pendingIndexEntry {
+		// Any key in [lastKey, key) separates the two blocks.
+		sep := util.FindShortestSeparator(self.options.Comparator, self.lastKey, key)
+		if err := self.addIndexEntry(sep); err != nil {
+			return err
+		}
+	}
+
+	if self.filterBlock != nil {
+		self.filterBlock.addKey(key)
+	}
+
+	if err := self.dataBlock.Add(key, value); err != nil {
+		return err
+	}
+
+	if self.props.NumEntries == 0 {
+		self.props.SmallestKey = append(Slice(nil), key...)
+	}
+	self.lastKey = append(self.lastKey[:0], key...)
+
+	self.props.NumEntries++
+	self.props.RawKeySize   += uint64(len(key))
+	self.props.RawValueSize += uint64(len(value))
+
+	if self.dataBlock.EstimatedSize() >= self.options.BlockSize {
+		return self.flush()
+	}
 	return nil
 }
 
-func (self ssTableWriter) Close() error {
-	return self.file.Close()
+func (self *ssTableWriter) Close() error {
+	if self.closed {
+		return ErrTableWriterClosed
+	}
+	self.closed = true
+
+	err := self.finish()
+	if self.closer != nil {
+		if cerr := self.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Writes the current data block to the file.
+func (self *ssTableWriter) flush() error {
+	if self.dataBlock.Empty() {
+		return nil
+	}
+
+	self.pendingHandle, self.err = self.finishBlock(self.dataBlock)
+	if self.err != nil {
+		return self.err
+	}
+	self.pendingIndexEntry = true
+
+	self.props.NumDataBlocks++
+	self.props.DataSize = self.offset
+
+	if self.filterBlock != nil && !self.partitionFilters {
+		self.filterBlock.startBlock(self.offset)
+	}
+
+	return self.dataBlock.Flush()
+}
+
+// Writes the remaining data block, the filter block, the index block, the 
+// properties block, the meta index block and the footer.
+func (self *ssTableWriter) finish() error {
+	if self.err != nil {
+		return self.err
+	}
+
+	if err := self.flush(); err != nil {
+		return err
+	}
+
+	if self.pendingIndexEntry {
+		succ := util.FindShortSuccessor(self.options.Comparator, self.lastKey)
+		if err := self.addIndexEntry(succ); err != nil {
+			return err
+		}
+		if self.partitionIndex && !self.indexBlock.Empty() {
+			if err := self.cutPartition(succ); err != nil {
+				return err
+			}
+		}
+	}
+
+	if self.filterBlock != nil {
+		if err := self.writeFilter(); err != nil {
+			return err
+		}
+	}
+
+	blockIndexHandle, err := self.writeIndex()
+	if err != nil {
+		return err
+	}
+
+	self.props.IndexSize += blockIndexHandle.Size + BlockTrailerSize
+	self.props.LargestKey = self.lastKey
+	self.props.ComparatorName = self.options.Comparator.Name()
+	self.props.CompressionName = self.options.Compression.String()
+	self.props.CreationTime = time.Now()
+
+	propsBlock, err := self.props.encode()
+	if err != nil {
+		return err
+	}
+	propsHandle, err := self.writeRawBlock(propsBlock, NoCompression)
+	if err != nil {
+		return err
+	}
+	if err = self.addMetaEntry(propertiesBlockName, propsHandle); err != nil {
+		return err
+	}
+
+	metaIndexHandle, err := self.finishBlock(self.metaBlock)
+	if err != nil {
+		return err
+	}
+
+	var buffer [VersionedFooterEncodedLength]byte
+
+	// LevelDB only knows about CRC32C checksums and Snappy compression, 
+	// other tables are written with a versioned footer.
+	footer := NewFooter(&metaIndexHandle, &blockIndexHandle)
+	switch {
+	case self.checksum != CRC32CChecksum,
+	     self.options.Compression != NoCompression && self.options.Compression != SnappyCompression:
+		footer = NewVersionedFooter(&metaIndexHandle, &blockIndexHandle, self.checksum)
+	}
+
+	n, err := footer.Encode(buffer[:])
+	if err != nil {
+		return err
+	}
+
+	return self.writeRaw(buffer[:n])
+}
+
+func (self *ssTableWriter) addIndexEntry(key Slice) error {
+	var buffer [MaxEncodedLength]byte
+
+	n, _ := self.pendingHandle.Encode(buffer[:])
+	self.pendingIndexEntry = false
+
+	if err := self.indexBlock.Add(key, buffer[:n]); err != nil {
+		return err
+	}
+
+	if self.partitionIndex && self.indexBlock.EstimatedSize() >= self.options.MetadataBlockSize {
+		return self.cutPartition(key)
+	}
+	return nil
+}
+
+// Index partition and its filter, key is the last key of the partition. 
+type partition struct {
+	key    Slice
+	index  Block
+	filter Block
+}
+
+// Finishes the current index partition, and the filter of its keys if the 
+// filter is partitioned.
+func (self *ssTableWriter) cutPartition(key Slice) error {
+	if err := self.indexBlock.Finish(); err != nil {
+		return err
+	}
+
+	p := partition{
+		key:   append(Slice(nil), key...),
+		index: append(Block(nil), self.indexBlock.Block()...),
+	}
+	if self.partitionFilters {
+		p.filter = self.filterBlock.finishPartition()
+	}
+	self.partitions = append(self.partitions, p)
+
+	return self.indexBlock.Flush()
+}
+
+// Writes the filter block, or the filter partitions followed by their 
+// top-level index, and adds it to the meta index.
+func (self *ssTableWriter) writeFilter() error {
+	name := self.options.FilterPolicy.Name()
+
+	self.props.FilterPolicyName = name
+	if self.options.PrefixExtractor != nil {
+		self.props.PrefixExtractorName = self.options.PrefixExtractor.Name()
+	}
+
+	if !self.partitionFilters {
+		filterHandle, err := self.writeRawBlock(self.filterBlock.finish(), NoCompression)
+		if err != nil {
+			return err
+		}
+		self.props.FilterSize = filterHandle.Size + BlockTrailerSize
+
+		return self.addMetaEntry(filterMetaPrefix + name, filterHandle)
+	}
+
+	top := NewBlockBuilder(1, self.options.Comparator)
+	for _, p := range self.partitions {
+		handle, err := self.writeRawBlock(p.filter, NoCompression)
+		if err != nil {
+			return err
+		}
+		self.props.FilterSize += handle.Size + BlockTrailerSize
+
+		if err := self.addHandle(top, p.key, handle); err != nil {
+			return err
+		}
+	}
+
+	topHandle, err := self.finishBlock(top)
+	if err != nil {
+		return err
+	}
+	self.props.FilterSize += topHandle.Size + BlockTrailerSize
+
+	return self.addMetaEntry(partitionedFilterMetaPrefix + name, topHandle)
+}
+
+// Writes the index partitions, and returns the handle of the index block or 
+// of the top-level index.
+func (self *ssTableWriter) writeIndex() (BlockHandle, error) {
+	if !self.partitionIndex {
+		return self.finishBlock(self.indexBlock)
+	}
+
+	top := NewBlockBuilder(1, self.options.Comparator)
+	for _, p := range self.partitions {
+		handle, err := self.writeBlock(p.index)
+		if err != nil {
+			return BlockHandle{}, err
+		}
+		self.props.IndexSize += handle.Size + BlockTrailerSize
+
+		if err := self.addHandle(top, p.key, handle); err != nil {
+			return BlockHandle{}, err
+		}
+	}
+	self.props.IndexPartitions = uint64(len(self.partitions))
+
+	return self.finishBlock(top)
+}
+
+// Adds an entry mapping key to the encoded handle.
+func (self *ssTableWriter) addHandle(b *BlockBuilder, key Slice, handle BlockHandle) error {
+	var buffer [MaxEncodedLength]byte
+
+	n, _ := handle.Encode(buffer[:])
+	return b.Add(key, buffer[:n])
+}
+
+func (self *ssTableWriter) addMetaEntry(name string, handle BlockHandle) error {
+	return self.addHandle(self.metaBlock, Slice(name), handle)
+}
+
+// Finishes the builder and writes its block to the file.
+func (self *ssTableWriter) finishBlock(b *BlockBuilder) (BlockHandle, error) {
+	if err := b.Finish(); err != nil {
+		return BlockHandle{}, err
+	}
+	return self.writeBlock(b.Block())
+}
+
+// Compresses the block with the table compression and writes it. The block
+// is stored uncompressed when compression saves less than 12.5%, or when it
+// is too large to be decompressed.
+func (self *ssTableWriter) writeBlock(b Block) (BlockHandle, error) {
+	if self.codec != nil && len(b) <= maxDecodedSize {
+		compressed, err := self.codec.Encode(self.compressed[:cap(self.compressed)], b)
+		if err != nil {
+			self.err = err
+			return BlockHandle{}, err
+		}
+		self.compressed = compressed
+
+		if len(compressed) < len(b) - len(b) / 8 {
+			return self.writeRawBlock(compressed, self.options.Compression)
+		}
+	}
+
+	return self.writeRawBlock(b, NoCompression)
+}
+
+// Writes the block contents followed by the block trailer and returns the
+// handle of the block.
+func (self *ssTableWriter) writeRawBlock(b Block, compression Compression) (BlockHandle, error) {
+	handle := BlockHandle{
+		Offset: self.offset,
+		Size:   uint64(len(b)),
+	}
+
+	// The checksum covers the block contents and the compression type.
+	b = append(b, byte(compression))
+	checksum := blockChecksum(self.checksum, b)
+	b = append(b, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[len(b) - 4:], checksum)
+
+	return handle, self.writeRaw(b)
+}
+
+func (self *ssTableWriter) writeRaw(data []byte) error {
+	n, err := self.writer.Write(data)
+	self.offset += uint64(n)
+	if err != nil {
+		self.err = err
+	}
+	return err
 }
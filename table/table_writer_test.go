@@ -0,0 +1,356 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(i int) Slice {
+	return Slice(fmt.Sprintf("key%06d", i))
+}
+
+func testValue(i int) Slice {
+	return Slice(fmt.Sprintf("value-%d", i))
+}
+
+// Writes n sequential keys to a new table and returns the file name.
+func writeTestTable(t testing.TB, n int, opt *Options) string {
+	filename := filepath.Join(t.TempDir(), "test.sst")
+
+	table, err := NewWriter(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < n; i++ {
+		if err := table.Write(testKey(i), testValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := table.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestWriterReadBack(t *testing.T) {
+	const n = 2000
+
+	filename := writeTestTable(t, n, DefaultOptions())
+
+	table, err := NewReader(filename, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	sst := table.(*ssTable)
+	if len(sst.BlockIndex) < 2 {
+		t.Errorf("Should have more than one data block, got %d", len(sst.BlockIndex))
+	}
+
+	for i := 0; i < n; i++ {
+		value, err := table.Read(testKey(i))
+		if err != nil {
+			t.Fatalf("Looking for %s: %v", testKey(i), err)
+		}
+		if string(value) != string(testValue(i)) {
+			t.Errorf("Looking for %s, got %s", testKey(i), value)
+		}
+	}
+
+	for _, key := range []string{"a", "key", "key000000a", "key001999a", "zzz"} {
+		if _, err := table.Read(Slice(key)); err != ErrNotFound {
+			t.Errorf("Looking for %s, should be ErrNotFound got %v", key, err)
+		}
+	}
+}
+
+func TestWriterEmptyTable(t *testing.T) {
+	filename := writeTestTable(t, 0, nil)
+
+	table, err := NewReader(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	if _, err := table.Read(Slice("key")); err != ErrNotFound {
+		t.Errorf("Should be ErrNotFound, got %v", err)
+	}
+}
+
+func TestWriterKeyOrder(t *testing.T) {
+	table, err := NewWriter(filepath.Join(t.TempDir(), "test.sst"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := table.Write(Slice("b"), Slice("1")); err != nil {
+		t.Error(err)
+	}
+	if err := table.Write(Slice("a"), Slice("2")); err != ErrTableKeyOrder {
+		t.Errorf("Should be ErrTableKeyOrder, got %v", err)
+	}
+	if err := table.Write(Slice("b"), Slice("3")); err != ErrTableKeyOrder {
+		t.Errorf("Should be ErrTableKeyOrder, got %v", err)
+	}
+
+	if err := table.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := table.Write(Slice("c"), Slice("4")); err != ErrTableWriterClosed {
+		t.Errorf("Should be ErrTableWriterClosed, got %v", err)
+	}
+}
+
+func TestWriterRestartInterval(t *testing.T) {
+	opt := DefaultOptions()
+	opt.BlockRestartInterval = 4
+
+	filename := writeTestTable(t, 100, opt)
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	sst := table.(*ssTable)
+	block, err := sst.readBlock(&sst.BlockIndex[0].Handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	iter := NewEntryIterator(block)
+	for entry, ok := iter.Next(); ok; entry, ok = iter.Next() {
+		if count % 4 == 0 && entry.Shared != 0 {
+			t.Errorf("Entry %d should be a restart point, got %v", count, entry)
+		}
+		count++
+	}
+
+	if restarts := block.NumberOfRestarts(); restarts != (count + 3) / 4 {
+		t.Errorf("Should have %d restarts, got %d", (count + 3) / 4, restarts)
+	}
+}
+
+func TestWriterApproximateOffsetOf(t *testing.T) {
+	const n = 10000
+
+	table := openTestTable(t, n, DefaultOptions())
+	defer table.Close()
+
+	var last uint64
+	for i := 0; i < n; i += 100 {
+		offset := table.ApproximateOffsetOf(testKey(i))
+		if offset < last {
+			t.Fatalf("Offsets should increase, %s at %d after %d", testKey(i), offset, last)
+		}
+		last = offset
+	}
+
+	// Entries have about the same size, the middle key is in the middle
+	// of the data blocks.
+	end := table.ApproximateOffsetOf(Slice("z"))
+	mid := table.ApproximateOffsetOf(testKey(n / 2))
+	if mid < end * 4 / 10 || mid > end * 6 / 10 {
+		t.Errorf("Offset of %s should be about %d, got %d", testKey(n / 2), end / 2, mid)
+	}
+}
+
+func TestWriterShortIndexKeys(t *testing.T) {
+	const n = 2000
+
+	key := func(i int) Slice {
+		return Slice(fmt.Sprintf("key%06d-%s", 2 * i, strings.Repeat("x", 32)))
+	}
+
+	var buf bytes.Buffer
+
+	w, err := NewStreamWriter(&buf, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := w.Write(key(i), testValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := NewReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	sst := table.(*ssTable)
+	if len(sst.BlockIndex) < 2 {
+		t.Fatalf("Should have more than one data block, got %d", len(sst.BlockIndex))
+	}
+	// Blocks ending at a carry over, e.g. key000178 and key000180, keep
+	// their last key.
+	shortened := 0
+	for _, ie := range sst.BlockIndex {
+		if len(ie.Key) < len(key(0)) {
+			shortened++
+		}
+	}
+	if shortened < len(sst.BlockIndex) / 2 {
+		t.Errorf("Most index keys should be shortened, got %d of %d", shortened, len(sst.BlockIndex))
+	}
+
+	for i := 0; i < n; i++ {
+		value, err := table.Read(key(i))
+		if err != nil {
+			t.Fatalf("Looking for %s: %v", key(i), err)
+		}
+		if string(value) != string(testValue(i)) {
+			t.Errorf("Looking for %s, got %s", key(i), value)
+		}
+	}
+}
+
+func TestWriterRewriteLevelDB(t *testing.T) {
+	const filename = "../data/h.no-compression.sst"
+
+	want, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := NewReader(filename, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	var buf bytes.Buffer
+
+	w, err := NewStreamWriter(&buf, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	it := table.Iterator()
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		if err := w.Write(it.Key(), it.Value()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.Close()
+
+	// The rewritten table has a properties block, LevelDB tables do not. 
+	// The data blocks and the index are the same, LevelDB shortens the 
+	// index keys the same way.
+	wantIndex := table.(*ssTable).BlockIndex
+	gotIndex  := got.(*ssTable).BlockIndex
+	if len(gotIndex) != len(wantIndex) {
+		t.Fatalf("Rewritten table should have %d data blocks, got %d", len(wantIndex), len(gotIndex))
+	}
+	for i := range wantIndex {
+		if !bytes.Equal(gotIndex[i].Key, wantIndex[i].Key) || gotIndex[i].Handle != wantIndex[i].Handle {
+			t.Errorf("Index entry %d: got %q %v, want %q %v", i, 
+				gotIndex[i].Key, gotIndex[i].Handle, wantIndex[i].Key, wantIndex[i].Handle)
+		}
+	}
+
+	last := wantIndex[len(wantIndex) - 1].Handle
+	end  := last.Offset + last.Size + BlockTrailerSize
+	if !bytes.Equal(buf.Bytes()[:end], want[:end]) {
+		t.Errorf("Rewritten data blocks differ from %s", filename)
+	}
+}
+
+// Orders the keys in reverse bytewise order.
+type reverseComparator struct{}
+
+func (reverseComparator) Name() string {
+	return "test.ReverseComparator"
+}
+
+func (reverseComparator) Compare(a, b []byte) int {
+	return bytes.Compare(b, a)
+}
+
+func TestWriterCustomComparator(t *testing.T) {
+	const n = 2000
+
+	opt := DefaultOptions()
+	opt.Comparator = reverseComparator{}
+
+	filename := filepath.Join(t.TempDir(), "reverse.sst")
+
+	w, err := NewWriter(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := n - 1; i >= 0; i-- {
+		if err := w.Write(testKey(i), testValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewReader(filename, DefaultOptions()); err != ErrTableComparator {
+		t.Fatalf("Opening with another comparator should be ErrTableComparator, got %v", err)
+	}
+
+	table, err := NewReader(filename, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	for i := 0; i < n; i++ {
+		value, err := table.Read(testKey(i))
+		if err != nil {
+			t.Fatalf("Looking for %s: %v", testKey(i), err)
+		}
+		if string(value) != string(testValue(i)) {
+			t.Errorf("Looking for %s, got %s", testKey(i), value)
+		}
+	}
+	if _, err := table.Read(Slice("key000000a")); err != ErrNotFound {
+		t.Errorf("Looking for key000000a, should be ErrNotFound got %v", err)
+	}
+
+	// Seek to the first key <= key001000a in bytewise order
+	it := table.Iterator()
+	if !it.Seek(Slice("key001000a")) || string(it.Key()) != string(testKey(1000)) {
+		t.Fatalf("Seek should find %s", testKey(1000))
+	}
+	if !it.Next() || string(it.Key()) != string(testKey(999)) {
+		t.Errorf("Next should find %s", testKey(999))
+	}
+
+	if table.ApproximateOffsetOf(testKey(0)) <= table.ApproximateOffsetOf(testKey(n - 1)) {
+		t.Errorf("Smaller keys should be at the end of the table")
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/entuerto/taigaDB/util"
+)
+
+//---------------------------------------------------------------------------------------
+// Verify Report
+//---------------------------------------------------------------------------------------
+
+// VerifyProblem is a problem found by Verify.
+type VerifyProblem struct {
+	// Offset of the block, or of the footer, with the problem
+	Offset uint64
+	// "footer", "meta index", "index", "index partition", "data", 
+	// "filter partition" or the meta index name of a meta block
+	Block string
+
+	Err error
+	// Description of the problem, may be empty
+	Detail string
+}
+
+func (self VerifyProblem) String() string {
+	if self.Detail == "" {
+		return fmt.Sprintf("%s block at %d: %v", self.Block, self.Offset, self.Err)
+	}
+	return fmt.Sprintf("%s block at %d: %v: %s", self.Block, self.Offset, self.Err, self.Detail)
+}
+
+// VerifyReport lists the problems found by Verify.
+type VerifyReport struct {
+	// Size of the table in bytes
+	Size int64
+
+	// Number of data blocks and entries read
+	NumDataBlocks int
+	NumEntries    uint64
+
+	Problems []VerifyProblem
+}
+
+// Returns true if no problem was found.
+func (self *VerifyReport) OK() bool {
+	return len(self.Problems) == 0
+}
+
+// Verify checks the table file at path: the footer magic, the checksum of
+// every block, the restart arrays and the entries of the blocks, the order 
+// of the keys under the comparator of the options and the consistency of 
+// the index with the data blocks. 
+//
+// Verify does not stop at the first problem, every problem found is listed 
+// in the report. The error is only set when the file cannot be read.
+func Verify(path string, opt *Options) (*VerifyReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return VerifyReaderAt(file, info.Size(), opt), nil
+}
+
+// VerifyReaderAt checks the table stored in the first size bytes of r, 
+// see Verify.
+func VerifyReaderAt(r io.ReaderAt, size int64, opt *Options) *VerifyReport {
+	if opt == nil {
+		opt = DefaultOptions()
+	}
+
+	v := &verifier{
+		sst: &ssTable{
+			reader: r,
+			size: size,
+			options: opt,
+		},
+		cmp: opt.Comparator,
+		report: &VerifyReport{
+			Size: size,
+		},
+	}
+	v.verify()
+
+	return v.report
+}
+
+//---------------------------------------------------------------------------------------
+// Verifier
+//---------------------------------------------------------------------------------------
+
+type verifier struct {
+	sst    *ssTable
+	cmp    util.Comparator
+	report *VerifyReport
+
+	// Nil if the table has no properties
+	props *Properties
+}
+
+func (self *verifier) problem(offset uint64, block string, err error, format string, args ...interface{}) {
+	self.report.Problems = append(self.report.Problems, VerifyProblem{
+		Offset: offset,
+		Block:  block,
+		Err:    err,
+		Detail: fmt.Sprintf(format, args...),
+	})
+}
+
+func (self *verifier) verify() {
+	if err := self.sst.readFooter(); err != nil {
+		var offset uint64
+		if self.report.Size > FooterEncodedLength {
+			offset = uint64(self.report.Size - FooterEncodedLength)
+		}
+		self.problem(offset, "footer", err, "")
+		return
+	}
+
+	self.verifyMetaIndex()
+	self.verifyIndex()
+
+	if self.props != nil && self.props.NumEntries != self.report.NumEntries {
+		self.problem(0, propertiesBlockName, ErrTableProperties, 
+			"%d entries recorded, %d entries read", self.props.NumEntries, self.report.NumEntries)
+	}
+}
+
+func (self *verifier) verifyMetaIndex() {
+	bh := *self.sst.MetaIndexHandle
+
+	b := self.readBlock(bh, "meta index")
+	if b == nil {
+		return
+	}
+
+	var entries []IndexEntry
+
+	self.checkBlock(b, bh, "meta index", util.BytewiseComparator{}, func(key, value Slice) {
+		ie := IndexEntry{Key: append(Slice(nil), key...)}
+		if _, err := ie.Handle.Decode(value); err != nil {
+			self.problem(bh.Offset, "meta index", err, "handle of %q", key)
+			return
+		}
+		entries = append(entries, ie)
+	})
+
+	for _, ie := range entries {
+		name := string(ie.Key)
+
+		if strings.HasPrefix(name, partitionedFilterMetaPrefix) {
+			for _, p := range self.readIndex(ie.Handle, name) {
+				self.readBlock(p.Handle, "filter partition")
+			}
+			continue
+		}
+
+		b := self.readBlock(ie.Handle, name)
+		if b == nil || name != propertiesBlockName {
+			continue
+		}
+		if !self.checkBlock(b, ie.Handle, name, util.BytewiseComparator{}, nil) {
+			continue
+		}
+
+		self.props = new(Properties)
+		if err := self.props.decode(b); err != nil {
+			self.problem(ie.Handle.Offset, name, err, "")
+			continue
+		}
+
+		if cmp := self.props.ComparatorName; cmp != "" && cmp != self.cmp.Name() {
+			self.problem(ie.Handle.Offset, name, ErrTableComparator, "table comparator %s", cmp)
+		}
+	}
+}
+
+func (self *verifier) verifyIndex() {
+	entries := self.readIndex(*self.sst.BlockIndexHandle, "index")
+
+	if self.props != nil && self.props.IndexPartitions > 0 {
+		partitions := entries
+		entries = nil
+
+		if self.props.IndexPartitions != uint64(len(partitions)) {
+			self.problem(self.sst.BlockIndexHandle.Offset, "index", ErrTableProperties, 
+				"%d index partitions recorded, %d partitions read", self.props.IndexPartitions, len(partitions))
+		}
+
+		for i, p := range partitions {
+			part := self.readIndex(p.Handle, "index partition")
+			if len(part) == 0 {
+				self.problem(p.Handle.Offset, "index partition", ErrTableIndex, "partition %d is empty", i)
+				continue
+			}
+
+			if i > 0 && self.cmp.Compare(part[0].Key, partitions[i - 1].Key) <= 0 {
+				self.problem(p.Handle.Offset, "index partition", ErrTableIndex, 
+					"first key %q of partition %d is not after the key %q of the previous partition", part[0].Key, i, partitions[i - 1].Key)
+			}
+			if last := part[len(part) - 1].Key; self.cmp.Compare(last, p.Key) > 0 {
+				self.problem(p.Handle.Offset, "index partition", ErrTableIndex, 
+					"last key %q of partition %d is after its key %q", last, i, p.Key)
+			}
+
+			entries = append(entries, part...)
+		}
+	}
+
+	// Last key of the previous data block, nil if it was not read
+	var prevKey Slice
+	// End of the previous data block
+	var end uint64
+
+	for i, ie := range entries {
+		if ie.Handle.Offset < end {
+			self.problem(ie.Handle.Offset, "data", ErrTableIndex, "block %d overlaps the previous block", i)
+		}
+		end = ie.Handle.Offset + ie.Handle.Size + BlockTrailerSize
+
+		b := self.readBlock(ie.Handle, "data")
+		if b == nil {
+			prevKey = nil
+			continue
+		}
+		self.report.NumDataBlocks++
+
+		var first, last Slice
+		var n int
+
+		self.checkBlock(b, ie.Handle, "data", self.cmp, func(key, value Slice) {
+			if n == 0 {
+				first = append(Slice(nil), key...)
+			}
+			last = append(last[:0], key...)
+			n++
+		})
+		self.report.NumEntries += uint64(n)
+
+		if n == 0 {
+			self.problem(ie.Handle.Offset, "data", ErrTableIndex, "block %d is empty", i)
+			continue
+		}
+
+		if prevKey != nil && self.cmp.Compare(first, prevKey) <= 0 {
+			self.problem(ie.Handle.Offset, "data", ErrTableKeyOrder, 
+				"first key %q of block %d is not after the last key %q of the previous block", first, i, prevKey)
+		}
+		if i > 0 && self.cmp.Compare(first, entries[i - 1].Key) <= 0 {
+			self.problem(ie.Handle.Offset, "data", ErrTableIndex, 
+				"first key %q of block %d is not after the index key %q of the previous block", first, i, entries[i - 1].Key)
+		}
+		if self.cmp.Compare(last, ie.Key) > 0 {
+			self.problem(ie.Handle.Offset, "data", ErrTableIndex, 
+				"last key %q of block %d is after its index key %q", last, i, ie.Key)
+		}
+
+		prevKey = last
+	}
+}
+
+// Reads and checks an index block, returns its entries.
+func (self *verifier) readIndex(bh BlockHandle, name string) []IndexEntry {
+	b := self.readBlock(bh, name)
+	if b == nil {
+		return nil
+	}
+
+	var entries []IndexEntry
+
+	self.checkBlock(b, bh, name, self.cmp, func(key, value Slice) {
+		ie := IndexEntry{Key: append(Slice(nil), key...)}
+		if _, err := ie.Handle.Decode(value); err != nil {
+			self.problem(bh.Offset, name, err, "handle of %q", key)
+			return
+		}
+		entries = append(entries, ie)
+	})
+
+	return entries
+}
+
+// Reads a block and verifies its checksum. Returns nil if the block cannot
+// be read.
+func (self *verifier) readBlock(bh BlockHandle, name string) Block {
+	if !self.sst.inTable(&bh) {
+		self.problem(bh.Offset, name, ErrBlockReadCorruption, 
+			"block of %d bytes past the end of the table", bh.Size)
+		return nil
+	}
+
+	b, err := self.sst.readBlockVerify(&bh, true)
+	if err != nil {
+		self.problem(bh.Offset, name, err, "")
+		return nil
+	}
+	return b
+}
+
+// Checks the restart array and the entries of a block, fn is called for 
+// each entry. Returns false if a problem was found.
+func (self *verifier) checkBlock(b Block, bh BlockHandle, name string, cmp util.Comparator, fn func(key, value Slice)) bool {
+	if len(b) < 4 {
+		self.problem(bh.Offset, name, ErrBlockRestarts, "block of %d bytes", len(b))
+		return false
+	}
+
+	packed := binary.LittleEndian.Uint32(b[len(b) - 4:])
+	n, buckets := b.footer()
+	if packed & blockHashIndexFlag != 0 && buckets == nil {
+		self.problem(bh.Offset, name, ErrBlockHashIndex, "hash index does not fit in a block of %d bytes", len(b))
+		return false
+	}
+
+	numRestarts := uint64(packed &^ blockHashIndexFlag)
+	if uint64(n) != numRestarts {
+		self.problem(bh.Offset, name, ErrBlockRestarts, "%d restarts in a block of %d bytes", numRestarts, len(b))
+		return false
+	}
+
+	for i, r := range buckets {
+		if r != hashIndexNoEntry && r != hashIndexCollision && uint64(r) >= numRestarts {
+			self.problem(bh.Offset, name, ErrBlockHashIndex, "bucket %d points to restart %d", i, r)
+			return false
+		}
+	}
+
+	limit := b.RestartStartOffset()
+	restart := func(i int) int {
+		return int(binary.LittleEndian.Uint32(b[limit + i * 4:]))
+	}
+
+	for i := 0; i < int(numRestarts); i++ {
+		// An empty block has one restart point at 0
+		if r := restart(i); (i == 0 && r != 0) || (i > 0 && (r >= limit || r <= restart(i - 1))) {
+			self.problem(bh.Offset, name, ErrBlockRestarts, "restart %d at offset %d", i, r)
+			return false
+		}
+	}
+	if numRestarts == 0 && limit > 0 {
+		self.problem(bh.Offset, name, ErrBlockRestarts, "entries without restart point")
+		return false
+	}
+
+	ok := true
+
+	var entry BlockEntry
+	var prevKey Slice
+	var next int
+
+	for pos := 0; pos < limit; {
+		isRestart := next < int(numRestarts) && restart(next) == pos
+		if isRestart {
+			next++
+		} else if next < int(numRestarts) && restart(next) < pos {
+			self.problem(bh.Offset, name, ErrBlockRestarts, "restart %d at offset %d is inside an entry", next, restart(next))
+			return false
+		}
+
+		rest, err := readBlockEntry(b[pos:limit], &entry)
+		if err != nil {
+			self.problem(bh.Offset, name, err, "entry at offset %d", pos)
+			return false
+		}
+		if isRestart && entry.Shared != 0 {
+			self.problem(bh.Offset, name, ErrBlockEntryCorruption, "entry at restart offset %d shares %d bytes", pos, entry.Shared)
+			return false
+		}
+
+		if pos > 0 && cmp.Compare(entry.Key, prevKey) <= 0 {
+			self.problem(bh.Offset, name, ErrTableKeyOrder, "key %q at offset %d is not after %q", entry.Key, pos, prevKey)
+			ok = false
+		}
+		prevKey = append(prevKey[:0], entry.Key...)
+
+		if buckets != nil {
+			r := int(buckets[blockKeyHash(entry.Key) % uint32(len(buckets))])
+			if r != hashIndexCollision && r != next - 1 {
+				self.problem(bh.Offset, name, ErrBlockHashIndex, 
+					"key %q at offset %d is in restart interval %d, the hash index points to %d", entry.Key, pos, next - 1, r)
+				ok = false
+			}
+		}
+
+		if fn != nil {
+			fn(entry.Key, entry.Value)
+		}
+
+		pos = limit - len(rest)
+	}
+
+	if next < int(numRestarts) && limit > 0 {
+		self.problem(bh.Offset, name, ErrBlockRestarts, "restart %d at offset %d is inside an entry", next, restart(next))
+		return false
+	}
+
+	return ok
+}
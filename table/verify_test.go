@@ -0,0 +1,189 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/entuerto/taigaDB/util"
+)
+
+func readTestTable(t *testing.T) []byte {
+	data, err := os.ReadFile("../data/h.no-compression.sst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func verifyBytes(data []byte, opt *Options) *VerifyReport {
+	return VerifyReaderAt(bytes.NewReader(data), int64(len(data)), opt)
+}
+
+// Checks the report has one problem with err at offset.
+func checkProblem(t *testing.T, report *VerifyReport, offset uint64, err error) {
+	if len(report.Problems) != 1 {
+		t.Fatalf("Should have one problem, got %v", report.Problems)
+	}
+	if p := report.Problems[0]; p.Offset != offset || p.Err != err {
+		t.Errorf("Should have %v at %d, got %v", err, offset, p)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	report, err := Verify("../data/h.no-compression.sst", DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("Should have no problem, got %v", report.Problems)
+	}
+	if report.NumDataBlocks != 4 || report.NumEntries != 1710 {
+		t.Errorf("Should read 4 data blocks and 1710 entries, got %d and %d", report.NumDataBlocks, report.NumEntries)
+	}
+
+	if _, err := Verify("missing.sst", DefaultOptions()); err == nil {
+		t.Errorf("Verifying a missing file should fail")
+	}
+}
+
+func TestVerifyWrittenTable(t *testing.T) {
+	opt := DefaultOptions()
+	opt.FilterPolicy = util.BloomFilterPolicy(10)
+	opt.Comparator = reverseComparator{}
+
+	var buf bytes.Buffer
+
+	w, err := NewStreamWriter(&buf, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 999; i >= 0; i-- {
+		if err := w.Write(testKey(i), testValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	report := verifyBytes(buf.Bytes(), opt)
+	if !report.OK() || report.NumEntries != 1000 {
+		t.Errorf("Should have 1000 entries and no problem, got %d %v", report.NumEntries, report.Problems)
+	}
+
+	// The bytewise order does not match the table
+	report = verifyBytes(buf.Bytes(), DefaultOptions())
+	if report.OK() {
+		t.Errorf("Should find problems with another comparator")
+	}
+	if p := report.Problems[0]; p.Block != propertiesBlockName || p.Err != ErrTableComparator {
+		t.Errorf("Should find the comparator mismatch, got %v", p)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := readTestTable(t)
+
+	// Second data block
+	data[5000] ^= 0xff
+
+	report := verifyBytes(data, DefaultOptions())
+	checkProblem(t, report, 4110, ErrBlockCRC32Corruption)
+
+	if report.NumDataBlocks != 3 {
+		t.Errorf("Should read the other 3 data blocks, got %d", report.NumDataBlocks)
+	}
+}
+
+func TestVerifyKeyOrder(t *testing.T) {
+	data := readTestTable(t)
+
+	// The first key of the first block, "a", becomes "z". The checksum is 
+	// updated to only find the key order problem.
+	if data[3] != 'a' {
+		t.Fatalf("Unexpected first key %q", data[3])
+	}
+	data[3] = 'z'
+
+	const size = 4105
+	binary.LittleEndian.PutUint32(data[size + 1:], blockChecksum(CRC32CChecksum, data[:size + 1]))
+
+	report := verifyBytes(data, DefaultOptions())
+	if report.OK() {
+		t.Fatal("Should find the key order problem")
+	}
+	for _, p := range report.Problems {
+		if p.Offset != 0 || p.Err != ErrTableKeyOrder {
+			t.Errorf("Should only find key order problems in the first block, got %v", p)
+		}
+	}
+}
+
+func TestVerifyFooter(t *testing.T) {
+	data := readTestTable(t)
+
+	data[len(data) - 1] ^= 0xff
+	checkProblem(t, verifyBytes(data, DefaultOptions()), uint64(len(data) - FooterEncodedLength), ErrTableMagicNumber)
+
+	checkProblem(t, verifyBytes(data[:10], DefaultOptions()), 0, ErrTableMagicNumber)
+}
+
+func TestVerifyTruncated(t *testing.T) {
+	data := readTestTable(t)
+
+	// Keep the footer, the blocks are past the end of the table
+	data = append(data[:4000], data[len(data) - FooterEncodedLength:]...)
+
+	report := verifyBytes(data, DefaultOptions())
+	if report.OK() {
+		t.Fatal("Should find problems")
+	}
+	for _, p := range report.Problems {
+		if p.Err != ErrBlockReadCorruption {
+			t.Errorf("Should only find blocks past the end, got %v", p)
+		}
+	}
+}
+
+// Without checksums, the corruptions reach the block decoding, which must 
+// report them without panics.
+func TestVerifyCorruptions(t *testing.T) {
+	opt := DefaultOptions()
+	opt.Checksum = NoChecksum
+	opt.BlockRestartInterval = 4
+	opt.BlockSize = 256
+
+	var buf bytes.Buffer
+
+	w, err := NewStreamWriter(&buf, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := w.Write(testKey(i), testValue(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if report := verifyBytes(buf.Bytes(), opt); !report.OK() {
+		t.Fatalf("Should have no problem, got %v", report.Problems)
+	}
+
+	for i := 0; i < buf.Len(); i++ {
+		for _, mask := range []byte{0x01, 0x80, 0xff} {
+			data := append([]byte(nil), buf.Bytes()...)
+			data[i] ^= mask
+
+			verifyBytes(data, opt)
+		}
+	}
+}
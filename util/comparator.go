@@ -26,6 +26,36 @@ type Comparator interface {
 	Name() string
 }
 
+// KeyShortener can be implemented by a Comparator to shorten the keys
+// stored in the index blocks of a table. The index of a table written with
+// a Comparator without KeyShortener stores full keys.
+type KeyShortener interface {
+	// If start < limit, returns a short key in [start, limit). Simple
+	// comparator implementations may return start, i.e., do nothing.
+	FindShortestSeparator(start, limit []byte) []byte
+
+	// Returns a short key >= key. Simple comparator implementations may
+	// return key, i.e., do nothing.
+	FindShortSuccessor(key []byte) []byte
+}
+
+// Returns a short key in [start, limit) if the comparator implements
+// KeyShortener, start otherwise.
+func FindShortestSeparator(cmp Comparator, start, limit []byte) []byte {
+	if ks, ok := cmp.(KeyShortener); ok {
+		return ks.FindShortestSeparator(start, limit)
+	}
+	return start
+}
+
+// Returns a short key >= key if the comparator implements KeyShortener,
+// key otherwise.
+func FindShortSuccessor(cmp Comparator, key []byte) []byte {
+	if ks, ok := cmp.(KeyShortener); ok {
+		return ks.FindShortSuccessor(key)
+	}
+	return key
+}
 
 type BytewiseComparator struct{}
 
@@ -39,6 +69,36 @@ func (BytewiseComparator) Compare(a, b []byte) int {
 	return bytes.Compare(a, b)
 }
 
+func (BytewiseComparator) FindShortestSeparator(start, limit []byte) []byte {
+	// Find length of common prefix
+	diff := SharedPrefix(start, limit)
+
+	if diff >= len(start) || diff >= len(limit) {
+		// Do not shorten if one string is a prefix of the other
+		return start
+	}
+
+	if b := start[diff]; b < 0xff && b + 1 < limit[diff] {
+		sep := append([]byte(nil), start[:diff + 1]...)
+		sep[diff]++
+		return sep
+	}
+	return start
+}
+
+func (BytewiseComparator) FindShortSuccessor(key []byte) []byte {
+	// Find first character that can be incremented
+	for i, b := range key {
+		if b != 0xff {
+			succ := append([]byte(nil), key[:i + 1]...)
+			succ[i]++
+			return succ
+		}
+	}
+	// key is a run of 0xffs. Leave it alone.
+	return key
+}
+
 // Returns the largest pos such that a[:pos] equals b[:pos].
 func SharedPrefix(a, b []byte) int {
 	i, n := 0, len(a)
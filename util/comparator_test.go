@@ -0,0 +1,74 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Comparator without KeyShortener
+type reverseComparator struct{}
+
+func (reverseComparator) Name() string {
+	return "test.ReverseComparator"
+}
+
+func (reverseComparator) Compare(a, b []byte) int {
+	return bytes.Compare(b, a)
+}
+
+func TestFindShortestSeparator(t *testing.T) {
+	testCases := []struct {
+		start, limit, want string
+	}{
+		{"abc", "abd", "abc"},
+		{"abc1", "abd", "abc1"},
+		{"abc1", "abe", "abd"},
+		{"abcdefghi", "abcz", "abce"},
+		{"abc", "abcdef", "abc"},
+		{"abcdef", "abc", "abcdef"},
+		{"ab\xffcd", "ab\xffz", "ab\xffd"},
+		{"a\xff", "b", "a\xff"},
+		{"", "a", ""},
+	}
+
+	var cmp BytewiseComparator
+	for _, tc := range testCases {
+		got := FindShortestSeparator(cmp, []byte(tc.start), []byte(tc.limit))
+		if string(got) != tc.want {
+			t.Errorf("FindShortestSeparator(%q, %q): got %q, want %q", tc.start, tc.limit, got, tc.want)
+		}
+		if tc.start < tc.limit && (string(got) < tc.start || string(got) >= tc.limit) {
+			t.Errorf("FindShortestSeparator(%q, %q): %q is out of range", tc.start, tc.limit, got)
+		}
+	}
+
+	if got := FindShortestSeparator(reverseComparator{}, []byte("b"), []byte("a")); string(got) != "b" {
+		t.Errorf("Comparator without KeyShortener should return start, got %q", got)
+	}
+}
+
+func TestFindShortSuccessor(t *testing.T) {
+	testCases := []struct {
+		key, want string
+	}{
+		{"abc", "b"},
+		{"\xff\xffabc", "\xff\xffb"},
+		{"\xff\xff", "\xff\xff"},
+		{"", ""},
+	}
+
+	var cmp BytewiseComparator
+	for _, tc := range testCases {
+		if got := FindShortSuccessor(cmp, []byte(tc.key)); string(got) != tc.want {
+			t.Errorf("FindShortSuccessor(%q): got %q, want %q", tc.key, got, tc.want)
+		}
+	}
+
+	if got := FindShortSuccessor(reverseComparator{}, []byte("abc")); string(got) != "abc" {
+		t.Errorf("Comparator without KeyShortener should return key, got %q", got)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"fmt"
+)
+
+// A PrefixExtractor extracts the prefix of keys. Tables written with a 
+// filter policy add the prefixes of their keys to the filters, a prefix 
+// scan then skips the tables without keys of the prefix. The keys with the 
+// same prefix must be contiguous in the order of the comparator.
+type PrefixExtractor interface {
+	// Name of the prefix extractor. The name is stored in the table, the 
+	// filter of a table written with another extractor is not used for 
+	// prefixes.
+	Name() string
+
+	// InDomain returns whether the key has a prefix.
+	InDomain(key []byte) bool
+
+	// Transform returns the prefix of a key in the domain. The prefix is a
+	// sub-slice of key.
+	Transform(key []byte) []byte
+}
+
+// FixedPrefixExtractor is a PrefixExtractor whose prefixes are the first n 
+// bytes of the keys. Shorter keys are not in the domain. It is compatible
+// with the fixed prefix transform of RocksDB.
+type FixedPrefixExtractor int
+
+func (p FixedPrefixExtractor) Name() string {
+	return fmt.Sprintf("rocksdb.FixedPrefix.%d", int(p))
+}
+
+func (p FixedPrefixExtractor) InDomain(key []byte) bool {
+	return len(key) >= int(p)
+}
+
+func (p FixedPrefixExtractor) Transform(key []byte) []byte {
+	if int(p) <= 0 {
+		return key[:0]
+	}
+	return key[:int(p)]
+}
+
+// CappedPrefixExtractor is a PrefixExtractor whose prefixes are the first 
+// n bytes of the keys, or the whole key if shorter. All keys are in the 
+// domain. It is compatible with the capped prefix transform of RocksDB.
+type CappedPrefixExtractor int
+
+func (p CappedPrefixExtractor) Name() string {
+	return fmt.Sprintf("rocksdb.CappedPrefix.%d", int(p))
+}
+
+func (p CappedPrefixExtractor) InDomain(key []byte) bool {
+	return true
+}
+
+func (p CappedPrefixExtractor) Transform(key []byte) []byte {
+	switch {
+	case int(p) <= 0:
+		return key[:0]
+	case len(key) < int(p):
+		return key
+	}
+	return key[:int(p)]
+}
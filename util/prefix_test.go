@@ -0,0 +1,45 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"testing"
+)
+
+func TestPrefixExtractors(t *testing.T) {
+	testCases := []struct {
+		extractor PrefixExtractor
+		name      string
+		key       string
+		inDomain  bool
+		prefix    string
+	}{
+		{FixedPrefixExtractor(3), "rocksdb.FixedPrefix.3", "abcdef", true, "abc"},
+		{FixedPrefixExtractor(3), "rocksdb.FixedPrefix.3", "abc", true, "abc"},
+		{FixedPrefixExtractor(3), "rocksdb.FixedPrefix.3", "ab", false, ""},
+		{FixedPrefixExtractor(0), "rocksdb.FixedPrefix.0", "abc", true, ""},
+		{CappedPrefixExtractor(3), "rocksdb.CappedPrefix.3", "abcdef", true, "abc"},
+		{CappedPrefixExtractor(3), "rocksdb.CappedPrefix.3", "ab", true, "ab"},
+		{CappedPrefixExtractor(3), "rocksdb.CappedPrefix.3", "", true, ""},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.extractor.Name(); got != tc.name {
+			t.Errorf("Name should be %s, got %s", tc.name, got)
+		}
+
+		key := []byte(tc.key)
+		if got := tc.extractor.InDomain(key); got != tc.inDomain {
+			t.Errorf("%s: InDomain(%q) should be %v", tc.name, tc.key, tc.inDomain)
+			continue
+		}
+		if !tc.inDomain {
+			continue
+		}
+		if got := string(tc.extractor.Transform(key)); got != tc.prefix {
+			t.Errorf("%s: Transform(%q) should be %q, got %q", tc.name, tc.key, tc.prefix, got)
+		}
+	}
+}
@@ -15,6 +15,41 @@ type QueryFilter interface {
 	KeyMayMatch(key []byte) bool
 }
 
+// A FilterPolicy creates the query filters stored in a table and reads them
+// back.
+type FilterPolicy interface {
+	// Name of the filter policy. The name is stored in the table, a filter 
+	// written with a different policy is ignored.
+	Name() string
+
+	// NewFilter appends to buf the encoded filter for the set of keys and 
+	// returns the extended buffer.
+	NewFilter(buf []byte, keys [][]byte) []byte
+
+	// QueryFilter returns the query filter for an encoded filter.
+	QueryFilter(data []byte) QueryFilter
+}
+
+// BloomFilterPolicy is a FilterPolicy that creates Bloom filters with the 
+// given number of bits per key. It is compatible with the C++ Level-DB 
+// built-in Bloom filter.
+type BloomFilterPolicy int
+
+func (p BloomFilterPolicy) Name() string {
+	// This string is part of the C++ Level-DB implementation's file format,
+	// and should not be changed.
+	return "leveldb.BuiltinBloomFilter2"
+}
+
+func (p BloomFilterPolicy) NewFilter(buf []byte, keys [][]byte) []byte {
+	f := NewBloomFilter(buf[len(buf):], keys, int(p)).(BloomFilter)
+	return append(buf, f...)
+}
+
+func (p BloomFilterPolicy) QueryFilter(data []byte) QueryFilter {
+	return BloomFilter(data)
+}
+
 // A Bloom Filter is an encoded set of []byte keys.
 type BloomFilter []byte
 
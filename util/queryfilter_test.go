@@ -145,3 +145,27 @@ func TestHash(t *testing.T) {
 		}
 	}
 }
+
+func TestBloomFilterPolicy(t *testing.T) {
+	policy := BloomFilterPolicy(10)
+
+	prefix := []byte("prefix")
+	buf := policy.NewFilter(prefix, [][]byte{
+		[]byte("hello"),
+		[]byte("world"),
+	})
+
+	if string(buf[:len(prefix)]) != "prefix" {
+		t.Fatalf("NewFilter should append to the buffer, got %q", buf[:len(prefix)])
+	}
+
+	want := NewBloomFilter(nil, [][]byte{[]byte("hello"), []byte("world")}, 10).(BloomFilter)
+	f := policy.QueryFilter(buf[len(prefix):]).(BloomFilter)
+
+	if f.String() != want.String() {
+		t.Fatalf("bits:\ngot  %q\nwant %q", f.String(), want.String())
+	}
+	if !f.KeyMayMatch([]byte("hello")) || f.KeyMayMatch([]byte("foo")) {
+		t.Error("Filter does not match the added keys")
+	}
+}
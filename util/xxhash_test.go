@@ -0,0 +1,30 @@
+// Copyright 2015 The taigaDB Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"testing"
+)
+
+func TestXXHash64(t *testing.T) {
+	// The magic want numbers come from the reference xxHash implementation.
+	testCases := []struct {
+		s    string
+		want uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"message digest", 0x066ed728fceeb3be},
+		{"abcdefghijklmnopqrstuvwxyz", 0xcfe1f278fa89835c},
+		{"The quick brown fox jumps over the lazy dog", 0x0b242d361fda71bc},
+		{"12345678901234567890123456789012345678901234567890123456789012345678901234567890", 0xe04a477f19ee145d},
+	}
+	for _, tc := range testCases {
+		if got := XXHash64([]byte(tc.s), 0); got != tc.want {
+			t.Errorf("s=%q: got 0x%016x, want 0x%016x", tc.s, got, tc.want)
+		}
+	}
+}